@@ -0,0 +1,60 @@
+package buttons
+
+import (
+	"maps"
+
+	"periph.io/x/conn/v3/gpio"
+)
+
+// Board describes how the buttons are wired.
+type Board struct {
+	Name string
+	// Pins maps each button to its pin name, as known to periph's gpioreg.
+	// Buttons not in the map are not watched.
+	Pins map[Button]string
+	// Active is the level read while a button is pressed.
+	Active gpio.Level
+	// Pull is the pull resistor set on every pin.
+	Pull gpio.Pull
+	// Lookup finds a pin by name. If nil, gpioreg.ByName is used after
+	// initializing periph's host drivers.
+	Lookup func(name string) gpio.PinIn
+}
+
+// Built-in board profiles. All of them have the buttons pulled up and
+// shorted to ground when pressed.
+var (
+	// PirateAudio is the current Pirate Audio wiring, with Y on GPIO24.
+	// https://pinout.xyz/pinout/pirate_audio_line_out#
+	PirateAudio = Board{
+		Name:   "Pirate Audio",
+		Pins:   map[Button]string{A: "GPIO5", B: "GPIO6", X: "GPIO16", Y: "GPIO24"},
+		Active: gpio.Low,
+		Pull:   gpio.PullUp,
+	}
+	// PirateAudioOld is the wiring of the first Pirate Audio revisions, with
+	// Y on GPIO20.
+	PirateAudioOld = Board{
+		Name:   "Pirate Audio (old)",
+		Pins:   map[Button]string{A: "GPIO5", B: "GPIO6", X: "GPIO16", Y: "GPIO20"},
+		Active: gpio.Low,
+		Pull:   gpio.PullUp,
+	}
+	// DisplayHATMini is the Pimoroni Display HAT Mini wiring.
+	// https://pinout.xyz/pinout/display_hat_mini
+	DisplayHATMini = Board{
+		Name:   "Display HAT Mini",
+		Pins:   map[Button]string{A: "GPIO5", B: "GPIO6", X: "GPIO16", Y: "GPIO24"},
+		Active: gpio.Low,
+		Pull:   gpio.PullUp,
+	}
+)
+
+// equal reports whether both boards are wired the same way. Boards with a
+// Lookup function are never considered equal.
+func (b Board) equal(o Board) bool {
+	if b.Lookup != nil || o.Lookup != nil {
+		return false
+	}
+	return b.Active == o.Active && b.Pull == o.Pull && maps.Equal(b.Pins, o.Pins)
+}
@@ -2,44 +2,116 @@ package buttons
 
 import (
 	"fmt"
-	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// Button identifies one or more of the hardware buttons. Values can be
+// combined with | to form a mask.
+type Button uint8
+
+const (
+	A Button = 1 << iota
+	B
+	X
+	Y
 
-	"periph.io/x/conn/v3/gpio"
-	"periph.io/x/conn/v3/gpio/gpioreg"
-	"periph.io/x/host/v3"
+	All = A | B | X | Y
 )
 
-func init() {
-	if _, err := host.Init(); err != nil {
-		log.Fatal(err)
+// buttons lists every single button, in pin order.
+var buttons = []Button{A, B, X, Y}
+
+func (b Button) String() string {
+	var names []string
+	for i, n := range "ABXY" {
+		if b&(1<<i) != 0 {
+			names = append(names, string(n))
+		}
+	}
+	if len(names) == 0 {
+		return "none"
 	}
+	return strings.Join(names, "+")
 }
 
-func OnButtonAPressed(fn func()) {
-	onButtonPressed(5, fn)
+// ParseButton parses button names joined with +, such as "A" or "A+Y", as
+// returned by Button.String. Names are case insensitive.
+func ParseButton(s string) (Button, error) {
+	var b Button
+	for _, n := range strings.Split(s, "+") {
+		i := strings.Index("ABXY", strings.ToUpper(n))
+		if len(n) != 1 || i < 0 {
+			return 0, fmt.Errorf("unknown button %q", n)
+		}
+		b |= 1 << i
+	}
+	return b, nil
 }
 
-func OnButtonBPressed(fn func()) {
-	onButtonPressed(6, fn)
+// Handler is a registered callback.
+type Handler struct {
+	sub     *subscriber
+	stopped atomic.Bool
 }
 
-func OnButtonXPressed(fn func()) {
-	onButtonPressed(16, fn)
+// Stop unregisters the handler. No new calls to its callback are made once
+// Stop returns. When no handlers or event channels are left, the pins stop
+// being watched and are restored to their previous settings.
+func (h *Handler) Stop() {
+	h.stopped.Store(true)
+	std.unsubscribe(h.sub)
 }
 
-func OnButtonYPressed(fn func()) {
-	onButtonPressed(24, fn)
+// Handle calls fn with every event of type t whose buttons are all in b,
+// until the returned handler is stopped.
+func Handle(b Button, t EventType, fn func(Event)) (*Handler, error) {
+	return handle(func(ev Event) bool {
+		return ev.Button&^b == 0 && ev.Type == t
+	}, fn)
 }
 
-func onButtonPressed(n int, fn func()) {
+// handle calls fn with the events accepted by match.
+func handle(match func(Event) bool, fn func(Event)) (*Handler, error) {
+	s, err := std.subscribe()
+	if err != nil {
+		return nil, err
+	}
+	h := &Handler{sub: s}
 	go func() {
-		p := gpioreg.ByName(fmt.Sprintf("GPIO%d", n))
-		if err := p.In(gpio.PullUp, gpio.FallingEdge); err != nil {
-			log.Fatal(err)
-		}
-		for {
-			p.WaitForEdge(-1)
-			fn()
+		for ev := range s.ch {
+			if match(ev) && !h.stopped.Load() {
+				fn(ev)
+			}
 		}
 	}()
+	return h, nil
+}
+
+// OnRepeat calls fn when a button in b is pressed and then on every Repeat
+// while it is held, as a key would auto-repeat.
+func OnRepeat(b Button, fn func(Event)) (*Handler, error) {
+	return handle(func(ev Event) bool {
+		return ev.Button&^b == 0 && (ev.Type == Pressed || ev.Type == Repeat)
+	}, fn)
+}
+
+func OnButtonAPressed(fn func()) (*Handler, error) {
+	return onButtonPressed(A, fn)
+}
+
+func OnButtonBPressed(fn func()) (*Handler, error) {
+	return onButtonPressed(B, fn)
+}
+
+func OnButtonXPressed(fn func()) (*Handler, error) {
+	return onButtonPressed(X, fn)
+}
+
+func OnButtonYPressed(fn func()) (*Handler, error) {
+	return onButtonPressed(Y, fn)
+}
+
+func onButtonPressed(b Button, fn func()) (*Handler, error) {
+	return Handle(b, Pressed, func(Event) { fn() })
 }
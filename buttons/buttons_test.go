@@ -0,0 +1,103 @@
+package buttons_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rubiojr/go-pirateaudio/buttons"
+	"github.com/rubiojr/go-pirateaudio/buttons/sim"
+	"periph.io/x/conn/v3/gpio"
+)
+
+// newSim returns simulated buttons read with opts, restoring the default
+// options when the test ends.
+func newSim(t *testing.T, opts buttons.Options) *sim.Sim {
+	t.Helper()
+	s := sim.New()
+	opts.Board = s.Board()
+	if err := buttons.Configure(opts); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		buttons.Configure(buttons.DefaultOpts)
+	})
+	return s
+}
+
+// waitFor waits until cond is true, failing the test after a while.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// waitWatched waits until the buttons watched are want.
+func waitWatched(t *testing.T, s *sim.Sim, want buttons.Button) {
+	t.Helper()
+	waitFor(t, "the buttons watched to be "+want.String(), func() bool {
+		return s.Watched() == want
+	})
+}
+
+// checkPulls checks that the pull of every simulated pin is want.
+func checkPulls(t *testing.T, s *sim.Sim, want gpio.Pull) {
+	t.Helper()
+	board := s.Board()
+	for b, name := range board.Pins {
+		if got := board.Lookup(name).Pull(); got != want {
+			t.Errorf("pin of %s has pull %s, want %s", b, got, want)
+		}
+	}
+}
+
+func TestHandlerStop(t *testing.T) {
+	s := newSim(t, buttons.DefaultOpts)
+
+	var pressed, released atomic.Int32
+	hp, err := buttons.OnButtonAPressed(func() { pressed.Add(1) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	hr, err := buttons.Handle(buttons.A, buttons.Released, func(buttons.Event) { released.Add(1) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := s.Watched(); w != buttons.All {
+		t.Fatalf("watching %s, want every button", w)
+	}
+	checkPulls(t, s, gpio.PullUp)
+
+	s.Click(buttons.A)
+	waitFor(t, "the first release", func() bool { return released.Load() == 1 })
+	if n := pressed.Load(); n != 1 {
+		t.Fatalf("pressed callback called %d times, want 1", n)
+	}
+
+	// The other handler keeps the buttons watched
+	hp.Stop()
+	if w := s.Watched(); w != buttons.All {
+		t.Fatalf("watching %s after the first Stop, want every button", w)
+	}
+	s.Click(buttons.A)
+	waitFor(t, "the second release", func() bool { return released.Load() == 2 })
+	if n := pressed.Load(); n != 1 {
+		t.Errorf("pressed callback called %d times after Stop, want 1", n)
+	}
+
+	hr.Stop()
+	if w := s.Watched(); w != 0 {
+		t.Fatalf("watching %s after the last Stop, want none", w)
+	}
+	checkPulls(t, s, gpio.Float)
+	s.Click(buttons.A)
+	time.Sleep(100 * time.Millisecond)
+	if n := released.Load(); n != 2 {
+		t.Errorf("released callback called %d times after Stop, want 2", n)
+	}
+}
@@ -0,0 +1,85 @@
+package buttons
+
+import "time"
+
+// OnChord calls fn when exactly the buttons in b are pressed together,
+// within Options.ChordWindow.
+func OnChord(b Button, fn func()) (*Handler, error) {
+	return handle(func(ev Event) bool {
+		return ev.Type == Chord && ev.Button == b
+	}, func(Event) { fn() })
+}
+
+// OnChordLongPress calls fn when exactly the buttons in b have been held
+// together for Options.LongPress.
+func OnChordLongPress(b Button, fn func()) (*Handler, error) {
+	return handle(func(ev Event) bool {
+		return ev.Type == ChordLongPress && ev.Button == b
+	}, func(Event) { fn() })
+}
+
+// OnSequence calls fn when the buttons in seq are pressed one after the
+// other, with no other button in between, and the first and last presses
+// are at most within apart.
+func OnSequence(seq []Button, within time.Duration, fn func()) (*Handler, error) {
+	return handle(newSequence(seq, within).match, func(Event) { fn() })
+}
+
+// sequence matches presses against a sequence of buttons.
+type sequence struct {
+	seq    []Button
+	within time.Duration
+	// border[i] is the length of the longest proper prefix of seq[:i+1]
+	// that is also a suffix of it, where a partial match resumes when the
+	// next press doesn't continue it.
+	border []int
+	times  []time.Time // presses of the partial match
+}
+
+func newSequence(seq []Button, within time.Duration) *sequence {
+	m := &sequence{
+		seq:    append([]Button(nil), seq...),
+		within: within,
+		border: make([]int, len(seq)),
+		times:  make([]time.Time, 0, len(seq)),
+	}
+	for i, k := 1, 0; i < len(seq); i++ {
+		for k > 0 && seq[i] != seq[k] {
+			k = m.border[k-1]
+		}
+		if seq[i] == seq[k] {
+			k++
+		}
+		m.border[i] = k
+	}
+	return m
+}
+
+// match reports whether ev completes the sequence.
+func (m *sequence) match(ev Event) bool {
+	if ev.Type != Pressed || len(m.seq) == 0 {
+		return false
+	}
+	for len(m.times) > 0 && ev.Time.Sub(m.times[0]) > m.within {
+		m.fallback()
+	}
+	for len(m.times) > 0 && ev.Button != m.seq[len(m.times)] {
+		m.fallback()
+	}
+	if ev.Button != m.seq[len(m.times)] {
+		return false
+	}
+	m.times = append(m.times, ev.Time)
+	if len(m.times) < len(m.seq) {
+		return false
+	}
+	m.times = m.times[:0]
+	return true
+}
+
+// fallback shortens the partial match to its longest proper suffix that is
+// also a prefix of the sequence.
+func (m *sequence) fallback() {
+	k := m.border[len(m.times)-1]
+	m.times = m.times[:copy(m.times, m.times[len(m.times)-k:])]
+}
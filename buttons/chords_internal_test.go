@@ -0,0 +1,47 @@
+package buttons
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSequenceMatch(t *testing.T) {
+	// press returns a press of b, ms after the start
+	press := func(b Button, ms int) Event {
+		return Event{Button: b, Type: Pressed, Time: time.Unix(0, 0).Add(time.Duration(ms) * time.Millisecond)}
+	}
+	for _, tc := range []struct {
+		name   string
+		seq    []Button
+		events []Event
+		want   []int // indices of the events completing the sequence
+	}{
+		{"simple", []Button{A, B}, []Event{press(A, 0), press(B, 10)}, []int{1}},
+		{"twice", []Button{A, B}, []Event{press(A, 0), press(B, 10), press(A, 20), press(B, 30)}, []int{1, 3}},
+		{"interrupted", []Button{A, B}, []Event{press(A, 0), press(X, 10), press(B, 20)}, nil},
+		{"restarted", []Button{A, B}, []Event{press(A, 0), press(A, 10), press(B, 20)}, []int{2}},
+		{"overlapping prefix", []Button{A, A, B}, []Event{press(A, 0), press(A, 10), press(A, 20), press(B, 30)}, []int{3}},
+		{"overlapping pairs", []Button{A, B, A, Y}, []Event{press(A, 0), press(B, 10), press(A, 20), press(B, 30), press(A, 40), press(Y, 50)}, []int{5}},
+		{"fallback to a shorter prefix", []Button{A, A, B, A, A, A}, []Event{press(A, 0), press(A, 10), press(B, 20), press(A, 30), press(A, 40), press(B, 50), press(A, 60), press(A, 70), press(A, 80)}, []int{8}},
+		{"no overlap after a match", []Button{A, A}, []Event{press(A, 0), press(A, 10), press(A, 20)}, []int{1}},
+		{"single button", []Button{Y}, []Event{press(Y, 0), press(X, 10), press(Y, 20)}, []int{0, 2}},
+		{"other events ignored", []Button{A, B}, []Event{press(A, 0), {Button: A, Type: Released, Time: time.Unix(0, 0)}, press(B, 10)}, []int{2}},
+		{"too slow", []Button{A, B}, []Event{press(A, 0), press(B, 1500)}, nil},
+		{"old presses dropped", []Button{A, A, B}, []Event{press(A, 0), press(A, 800), press(A, 1100), press(B, 1500)}, []int{3}},
+		{"empty", nil, []Event{press(A, 0)}, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m := newSequence(tc.seq, time.Second)
+			var got []int
+			for i, ev := range tc.events {
+				if m.match(ev) {
+					got = append(got, i)
+				}
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("matched at %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
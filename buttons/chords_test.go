@@ -0,0 +1,100 @@
+package buttons_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rubiojr/go-pirateaudio/buttons"
+	"github.com/rubiojr/go-pirateaudio/buttons/sim"
+)
+
+// count returns a callback counting its calls in n.
+func count(n *atomic.Int32) func() {
+	return func() { n.Add(1) }
+}
+
+// holdAB presses A and then B within the chord window, and releases them
+// in the same order after d, so the events come in a known order.
+func holdAB(s *sim.Sim, d time.Duration) {
+	const gap = 30 * time.Millisecond
+	s.Press(buttons.A)
+	time.Sleep(gap)
+	s.Press(buttons.B)
+	time.Sleep(d)
+	s.Release(buttons.A)
+	time.Sleep(gap)
+	s.Release(buttons.B)
+}
+
+func TestChord(t *testing.T) {
+	opts := buttons.DefaultOpts
+	opts.LongPress = 300 * time.Millisecond
+	opts.DoubleClick = 0
+	opts.RepeatDelay = 0
+	s := newSim(t, opts)
+	events := listen(t)
+
+	var ab, abx, long atomic.Int32
+	for _, h := range []func() (*buttons.Handler, error){
+		func() (*buttons.Handler, error) { return buttons.OnChord(buttons.A|buttons.B, count(&ab)) },
+		func() (*buttons.Handler, error) { return buttons.OnChord(buttons.A|buttons.B|buttons.X, count(&abx)) },
+		func() (*buttons.Handler, error) { return buttons.OnChordLongPress(buttons.A|buttons.B, count(&long)) },
+	} {
+		h, err := h()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer h.Stop()
+	}
+
+	holdAB(s, sim.ClickDuration)
+	evs := expect(t, events, "A Pressed", "B Pressed", "A+B Chord")
+	if evs[2].Time != evs[1].Time {
+		t.Errorf("chord at %v, want at the last press %v", evs[2].Time, evs[1].Time)
+	}
+	expect(t, events, "A Released", "B Released")
+	waitFor(t, "the chord callback", func() bool { return ab.Load() == 1 })
+
+	// Held long enough, one ChordLongPress
+	holdAB(s, 2*opts.LongPress)
+	expect(t, events, "A Pressed", "B Pressed", "A+B Chord", "A LongPress", "B LongPress", "A+B ChordLongPress", "A Released", "B Released")
+	waitFor(t, "the chord long press callback", func() bool { return long.Load() == 1 })
+
+	// Pressed further apart than the chord window
+	s.Press(buttons.A)
+	time.Sleep(opts.ChordWindow + 50*time.Millisecond)
+	s.Press(buttons.B)
+	time.Sleep(opts.LongPress + 50*time.Millisecond)
+	s.Release(buttons.A)
+	time.Sleep(30 * time.Millisecond)
+	s.Release(buttons.B)
+	expect(t, events, "A Pressed", "B Pressed", "A LongPress", "B LongPress", "A Released", "B Released")
+
+	quiet(t, events, 100*time.Millisecond)
+	if n := ab.Load(); n != 2 {
+		t.Errorf("A+B chord callback called %d times, want 2", n)
+	}
+	if n := abx.Load(); n != 0 {
+		t.Errorf("A+B+X chord callback called %d times, want 0", n)
+	}
+	if n := long.Load(); n != 1 {
+		t.Errorf("A+B chord long press callback called %d times, want 1", n)
+	}
+}
+
+func TestSequence(t *testing.T) {
+	s := newSim(t, buttons.DefaultOpts)
+
+	var n atomic.Int32
+	h, err := buttons.OnSequence([]buttons.Button{buttons.A, buttons.A, buttons.B}, time.Second, count(&n))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Stop()
+
+	clicks(s, buttons.A, 3)
+	time.Sleep(sim.ClickDuration)
+	s.Click(buttons.B)
+	waitFor(t, "the sequence callback", func() bool { return n.Load() == 1 })
+}
@@ -0,0 +1,156 @@
+package buttons
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type EventType uint8
+
+const (
+	Pressed     EventType = 1 // the button went down
+	Released    EventType = 2 // the button went up, Duration is how long it was held
+	LongPress   EventType = 3 // the button has been held for Options.LongPress
+	DoubleClick EventType = 4 // the button went down again within Options.DoubleClick
+	Repeat      EventType = 5 // the button is still held, sent every Options.RepeatInterval or faster
+	// Chord and ChordLongPress have several buttons in Event.Button
+	Chord          EventType = 6 // the buttons went down within Options.ChordWindow
+	ChordLongPress EventType = 7 // the chord has been held for Options.LongPress
+)
+
+func (t EventType) String() string {
+	switch t {
+	case Pressed:
+		return "Pressed"
+	case Released:
+		return "Released"
+	case LongPress:
+		return "LongPress"
+	case DoubleClick:
+		return "DoubleClick"
+	case Repeat:
+		return "Repeat"
+	case Chord:
+		return "Chord"
+	case ChordLongPress:
+		return "ChordLongPress"
+	}
+	return fmt.Sprintf("EventType(%d)", uint8(t))
+}
+
+// Event is something that happened to a button.
+type Event struct {
+	Button Button
+	Type   EventType
+	Time   time.Time
+	// Duration is how long the button has been held, for every event but
+	// Pressed, DoubleClick and Chord.
+	Duration time.Duration
+}
+
+func (e Event) String() string {
+	return fmt.Sprintf("%s %s", e.Button, e.Type)
+}
+
+// Options defines the timings used to derive events from presses.
+type Options struct {
+	// LongPress is how long a button has to be held to send LongPress,
+	// 0 disables it.
+	LongPress time.Duration
+	// DoubleClick is the longest time between a release and the next press
+	// of the same button to send DoubleClick, 0 disables it.
+	DoubleClick time.Duration
+	// RepeatDelay is how long a button has to be held before the first
+	// Repeat, 0 disables repeats.
+	RepeatDelay time.Duration
+	// RepeatInterval is the time between Repeat events.
+	RepeatInterval time.Duration
+	// RepeatAccel multiplies the time between Repeat events after each
+	// one, down to RepeatMinInterval. Values between 0 and 1 speed repeats
+	// up the longer a button is held, 0 keeps RepeatInterval.
+	RepeatAccel float64
+	// RepeatMinInterval is the shortest time between Repeat events when
+	// accelerating.
+	RepeatMinInterval time.Duration
+	// ChordWindow is the longest time between the first and the last press
+	// of buttons held together to send Chord, 0 disables chords.
+	ChordWindow time.Duration
+	// Debounce is how long a button level has to stay the same after an
+	// edge to count as a press or release, 0 disables debouncing.
+	Debounce time.Duration
+	// ButtonDebounce overrides Debounce for single buttons.
+	ButtonDebounce map[Button]time.Duration
+	// Board is the wiring of the buttons, PirateAudio if Pins is empty.
+	Board Board
+}
+
+// DefaultOpts is the recommended default options.
+var DefaultOpts = Options{
+	LongPress:      time.Second,
+	DoubleClick:    300 * time.Millisecond,
+	RepeatDelay:    500 * time.Millisecond,
+	RepeatInterval: 100 * time.Millisecond,
+	ChordWindow:    150 * time.Millisecond,
+	Debounce:       20 * time.Millisecond,
+	Board:          PirateAudio,
+}
+
+// debounce returns the debouncing window of b.
+func (o Options) debounce(b Button) time.Duration {
+	if d, ok := o.ButtonDebounce[b]; ok {
+		return d
+	}
+	return o.Debounce
+}
+
+// nextRepeatInterval returns the time to wait after a Repeat sent d after
+// the previous one.
+func (o Options) nextRepeatInterval(d time.Duration) time.Duration {
+	if o.RepeatAccel <= 0 || o.RepeatAccel >= 1 {
+		return o.RepeatInterval
+	}
+	return max(time.Duration(float64(d)*o.RepeatAccel), o.RepeatMinInterval, time.Millisecond)
+}
+
+// board returns the wiring to use.
+func (o Options) board() Board {
+	if len(o.Board.Pins) == 0 {
+		return PirateAudio
+	}
+	return o.Board
+}
+
+// Configure changes the options used from now on. If the buttons are being
+// watched and the board changed, the old pins are restored and the new
+// ones watched instead.
+func Configure(opts Options) error {
+	return std.configure(opts)
+}
+
+// Bounces returns how many edges debouncing has filtered out for the
+// buttons in b since the program started.
+func Bounces(b Button) uint64 {
+	var n uint64
+	for _, bb := range buttons {
+		if b&bb != 0 {
+			n += std.bounces[index(bb)].Load()
+		}
+	}
+	return n
+}
+
+// Events returns a channel delivering the events of every button until ctx
+// is done, when it is closed. The channel must be drained, as events are
+// delivered to every listener in order.
+func Events(ctx context.Context) (<-chan Event, error) {
+	s, err := std.subscribe()
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		<-ctx.Done()
+		std.unsubscribe(s)
+	}()
+	return s.ch, nil
+}
@@ -0,0 +1,35 @@
+package buttons
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNextRepeatInterval(t *testing.T) {
+	const ms = time.Millisecond
+	for _, tc := range []struct {
+		name  string
+		accel float64
+		min   time.Duration
+		want  []time.Duration
+	}{
+		{"accelerating", 0.5, 20 * ms, []time.Duration{50 * ms, 25 * ms, 20 * ms, 20 * ms}},
+		{"slow acceleration", 0.9, 70 * ms, []time.Duration{90 * ms, 81 * ms, 72900 * time.Microsecond, 70 * ms}},
+		{"no floor", 0.1, 0, []time.Duration{10 * ms, 1 * ms, 1 * ms}},
+		{"disabled", 0, 20 * ms, []time.Duration{100 * ms, 100 * ms}},
+		{"not slowing down", 1.5, 20 * ms, []time.Duration{100 * ms, 100 * ms}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			o := Options{RepeatInterval: 100 * ms, RepeatAccel: tc.accel, RepeatMinInterval: tc.min}
+			var got []time.Duration
+			for d := o.RepeatInterval; len(got) < len(tc.want); {
+				d = o.nextRepeatInterval(d)
+				got = append(got, d)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("intervals %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
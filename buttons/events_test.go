@@ -0,0 +1,218 @@
+package buttons_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rubiojr/go-pirateaudio/buttons"
+	"github.com/rubiojr/go-pirateaudio/buttons/sim"
+)
+
+// listen returns the events of every button until the test ends.
+func listen(t *testing.T) <-chan buttons.Event {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	events, err := buttons.Events(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+// next returns the next event, failing the test if none comes in time.
+func next(t *testing.T, events <-chan buttons.Event) buttons.Event {
+	t.Helper()
+	select {
+	case ev := <-events:
+		return ev
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for an event")
+	}
+	return buttons.Event{}
+}
+
+// expect checks that the next events are want, as formatted by
+// Event.String, and returns them.
+func expect(t *testing.T, events <-chan buttons.Event, want ...string) []buttons.Event {
+	t.Helper()
+	var got []buttons.Event
+	for i, w := range want {
+		ev := next(t, events)
+		if ev.String() != w {
+			t.Fatalf("event %d is %q, want %q (previous: %v)", i, ev, w, got)
+		}
+		got = append(got, ev)
+	}
+	return got
+}
+
+// quiet checks that no event comes for d.
+func quiet(t *testing.T, events <-chan buttons.Event, d time.Duration) {
+	t.Helper()
+	select {
+	case ev := <-events:
+		t.Fatalf("got unexpected event %q", ev)
+	case <-time.After(d):
+	}
+}
+
+// between checks that d is within [min, max].
+func between(t *testing.T, what string, d, min, max time.Duration) {
+	t.Helper()
+	if d < min || d > max {
+		t.Errorf("%s is %v, want between %v and %v", what, d, min, max)
+	}
+}
+
+// clicks clicks b n times, leaving a gap longer than the debouncing window
+// between the clicks.
+func clicks(s *sim.Sim, b buttons.Button, n int) {
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			time.Sleep(sim.ClickDuration)
+		}
+		s.Click(b)
+	}
+}
+
+func TestPressRelease(t *testing.T) {
+	opts := buttons.DefaultOpts
+	opts.LongPress = 0
+	opts.RepeatDelay = 0
+	s := newSim(t, opts)
+	events := listen(t)
+
+	s.Hold(buttons.B, 200*time.Millisecond)
+	evs := expect(t, events, "B Pressed", "B Released")
+	if evs[0].Duration != 0 {
+		t.Errorf("Pressed has duration %v, want 0", evs[0].Duration)
+	}
+	// Both edges are delayed by the same debouncing window
+	between(t, "Released duration", evs[1].Duration, 150*time.Millisecond, 300*time.Millisecond)
+	if got := evs[1].Time.Sub(evs[0].Time); got != evs[1].Duration {
+		t.Errorf("Released duration is %v, want the %v between the events", evs[1].Duration, got)
+	}
+	quiet(t, events, 100*time.Millisecond)
+}
+
+func TestLongPress(t *testing.T) {
+	opts := buttons.DefaultOpts
+	opts.LongPress = 150 * time.Millisecond
+	opts.DoubleClick = 0
+	opts.RepeatDelay = 0
+	s := newSim(t, opts)
+	events := listen(t)
+
+	s.Click(buttons.A)
+	expect(t, events, "A Pressed", "A Released")
+
+	s.Hold(buttons.A, 300*time.Millisecond)
+	evs := expect(t, events, "A Pressed", "A LongPress", "A Released")
+	between(t, "LongPress duration", evs[1].Duration, opts.LongPress, opts.LongPress+50*time.Millisecond)
+	if evs[2].Duration < evs[1].Duration {
+		t.Errorf("Released duration %v is shorter than the LongPress one %v", evs[2].Duration, evs[1].Duration)
+	}
+	quiet(t, events, 100*time.Millisecond)
+}
+
+func TestDoubleClick(t *testing.T) {
+	opts := buttons.DefaultOpts
+	opts.LongPress = 0
+	opts.RepeatDelay = 0
+	opts.DoubleClick = 300 * time.Millisecond
+	s := newSim(t, opts)
+	events := listen(t)
+
+	// The third click doesn't make another double click with the second
+	// one, the fourth does with the third
+	clicks(s, buttons.X, 4)
+	expect(t, events,
+		"X Pressed", "X Released",
+		"X Pressed", "X DoubleClick", "X Released",
+		"X Pressed", "X Released",
+		"X Pressed", "X DoubleClick", "X Released",
+	)
+	quiet(t, events, 100*time.Millisecond)
+
+	// Too slow
+	s.Click(buttons.X)
+	time.Sleep(opts.DoubleClick + 50*time.Millisecond)
+	s.Click(buttons.X)
+	expect(t, events, "X Pressed", "X Released", "X Pressed", "X Released")
+	quiet(t, events, 100*time.Millisecond)
+}
+
+func TestDebounce(t *testing.T) {
+	opts := buttons.DefaultOpts
+	opts.LongPress = 0
+	opts.DoubleClick = 0
+	opts.RepeatDelay = 0
+	opts.Debounce = 20 * time.Millisecond
+	opts.ButtonDebounce = map[buttons.Button]time.Duration{buttons.X: 200 * time.Millisecond}
+	s := newSim(t, opts)
+	events := listen(t)
+
+	// bounce presses b, bouncing twice after gap
+	bounce := func(b buttons.Button, gap time.Duration) {
+		s.Press(b)
+		time.Sleep(gap)
+		s.Release(b)
+		time.Sleep(5 * time.Millisecond)
+		s.Press(b)
+	}
+
+	before := buttons.Bounces(buttons.A)
+	bounce(buttons.A, 5*time.Millisecond)
+	expect(t, events, "A Pressed")
+	if n := buttons.Bounces(buttons.A) - before; n != 2 {
+		t.Errorf("A bounced %d times, want 2", n)
+	}
+	s.Release(buttons.A)
+	expect(t, events, "A Released")
+
+	// Longer than Debounce, but within the window of X
+	all := buttons.Bounces(buttons.All)
+	before = buttons.Bounces(buttons.X)
+	bounce(buttons.X, 50*time.Millisecond)
+	expect(t, events, "X Pressed")
+	if n := buttons.Bounces(buttons.X) - before; n != 2 {
+		t.Errorf("X bounced %d times, want 2", n)
+	}
+	s.Release(buttons.X)
+	expect(t, events, "X Released")
+	quiet(t, events, 100*time.Millisecond)
+	if n := buttons.Bounces(buttons.All) - all; n != 2 {
+		t.Errorf("buttons bounced %d times after the X press, want 2", n)
+	}
+}
+
+func TestRepeatAccel(t *testing.T) {
+	opts := buttons.DefaultOpts
+	opts.LongPress = 0
+	opts.RepeatDelay = 100 * time.Millisecond
+	opts.RepeatInterval = 160 * time.Millisecond
+	opts.RepeatAccel = 0.5
+	opts.RepeatMinInterval = 40 * time.Millisecond
+	s := newSim(t, opts)
+	events := listen(t)
+
+	// Repeats at 100, 260, 340, 380, 420, 460 and 500ms
+	s.Hold(buttons.Y, 520*time.Millisecond)
+	var repeats []time.Time
+	for ev := next(t, events); ev.Type != buttons.Released; ev = next(t, events) {
+		if ev.Type == buttons.Repeat {
+			repeats = append(repeats, ev.Time)
+		}
+	}
+	if len(repeats) < 5 {
+		t.Fatalf("got %d repeats, want at least 5", len(repeats))
+	}
+	const slack = 20 * time.Millisecond
+	between(t, "first interval", repeats[1].Sub(repeats[0]), opts.RepeatInterval-slack, opts.RepeatInterval+slack)
+	between(t, "second interval", repeats[2].Sub(repeats[1]), opts.RepeatInterval/2-slack, opts.RepeatInterval/2+slack)
+	for i := 3; i < len(repeats); i++ {
+		between(t, "interval after the floor", repeats[i].Sub(repeats[i-1]), opts.RepeatMinInterval-slack, opts.RepeatMinInterval+slack)
+	}
+}
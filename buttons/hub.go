@@ -0,0 +1,518 @@
+package buttons
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/bits"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"periph.io/x/conn/v3/gpio"
+	"periph.io/x/conn/v3/gpio/gpioreg"
+	"periph.io/x/host/v3"
+)
+
+// pollInterval bounds how long a watcher waits for an edge before checking
+// whether it has been stopped.
+const pollInterval = 100 * time.Millisecond
+
+// std is the hub behind the package level functions.
+var std = &hub{opts: DefaultOpts}
+
+// transition is a change in the level of a button.
+type transition struct {
+	button  Button
+	pressed bool
+	time    time.Time
+}
+
+// buttonState tracks a button to derive events from its transitions.
+type buttonState struct {
+	pressed     bool
+	since       time.Time // when it was pressed
+	lastRelease time.Time // zero if the next press can't be a double click
+	double      bool      // the current press completed a double click
+	longSent    bool
+	nextRepeat  time.Time
+	interval    time.Duration // until the repeat after nextRepeat
+}
+
+type subscriber struct {
+	in   chan Event
+	ch   chan Event
+	done chan struct{}
+}
+
+// hub watches the button pins while there are subscribers and sends them
+// the events derived from the presses.
+type hub struct {
+	// life serializes starting and stopping sessions, so the pins of a
+	// stopping session are restored before they are opened again. It is
+	// taken before mu, which the session goroutines use.
+	life    sync.Mutex
+	mu      sync.Mutex
+	opts    Options
+	subs    map[*subscriber]struct{}
+	session *session
+	bounces [4]atomic.Uint64 // indexed by button bit
+}
+
+// session is the time between the first subscription and the last
+// unsubscription, during which the pins are watched.
+type session struct {
+	h      *hub
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	raw    chan transition
+	board  Board
+	lookup func(name string) gpio.PinIn
+	pins   map[Button]gpio.PinIn
+	pulls  map[Button]gpio.Pull // pull of each pin before the session
+	held   atomic.Uint32        // buttons pressed, for State
+	// Owned by the run goroutine
+	states map[Button]*buttonState
+	chord  chordState
+}
+
+// chordState tracks the buttons held together.
+type chordState struct {
+	buttons  Button // zero if there is no chord
+	since    time.Time
+	longSent bool
+}
+
+// configure changes the options, moving the watchers to the new pins if
+// the board changed while the hub is running.
+func (h *hub) configure(opts Options) error {
+	h.life.Lock()
+	defer h.life.Unlock()
+	h.mu.Lock()
+	h.opts = opts
+	ss := h.session
+	h.mu.Unlock()
+	if ss == nil || ss.board.equal(opts.board()) {
+		return nil
+	}
+	ss.stop()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ss, err := h.start()
+	if err != nil {
+		h.session = nil
+		return err
+	}
+	h.session = ss
+	return nil
+}
+
+func (h *hub) options() Options {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.opts
+}
+
+// subscribe registers a new listener, starting the hub if needed.
+func (h *hub) subscribe() (*subscriber, error) {
+	h.life.Lock()
+	defer h.life.Unlock()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.session == nil {
+		ss, err := h.start()
+		if err != nil {
+			return nil, err
+		}
+		h.session = ss
+	}
+	s := &subscriber{
+		in:   make(chan Event, 64),
+		ch:   make(chan Event),
+		done: make(chan struct{}),
+	}
+	go s.forward()
+	if h.subs == nil {
+		h.subs = map[*subscriber]struct{}{}
+	}
+	h.subs[s] = struct{}{}
+	return s, nil
+}
+
+// unsubscribe removes a listener, stopping the hub after the last one.
+func (h *hub) unsubscribe(s *subscriber) {
+	h.life.Lock()
+	defer h.life.Unlock()
+	h.mu.Lock()
+	if _, ok := h.subs[s]; !ok {
+		h.mu.Unlock()
+		return
+	}
+	delete(h.subs, s)
+	close(s.done)
+	ss := h.session
+	if len(h.subs) > 0 || ss == nil {
+		h.mu.Unlock()
+		return
+	}
+	h.session = nil
+	// The session goroutines take h.mu to emit events
+	h.mu.Unlock()
+	ss.stop()
+}
+
+// inject feeds a transition to the running session, as if it had been
+// read from the pins.
+func (h *hub) inject(ctx context.Context, t transition) error {
+	h.mu.Lock()
+	ss := h.session
+	h.mu.Unlock()
+	if ss == nil {
+		return errors.New("buttons: not watching the buttons")
+	}
+	select {
+	case ss.raw <- t:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// forward passes events to the subscriber's channel until it is done.
+func (s *subscriber) forward() {
+	defer close(s.ch)
+	for {
+		select {
+		case ev := <-s.in:
+			select {
+			case s.ch <- ev:
+			case <-s.done:
+				return
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// newSession returns a session for the current board. h.mu must be held.
+func (h *hub) newSession() (*session, error) {
+	board := h.opts.board()
+	lookup := board.Lookup
+	if lookup == nil {
+		if _, err := host.Init(); err != nil {
+			return nil, err
+		}
+		lookup = byName
+	}
+	ss := &session{
+		h:      h,
+		raw:    make(chan transition),
+		board:  board,
+		lookup: lookup,
+		pins:   map[Button]gpio.PinIn{},
+		pulls:  map[Button]gpio.Pull{},
+		states: map[Button]*buttonState{},
+	}
+	for _, b := range buttons {
+		ss.states[b] = &buttonState{}
+	}
+	return ss, nil
+}
+
+// byName looks a pin up in periph's registry.
+func byName(name string) gpio.PinIn {
+	if p := gpioreg.ByName(name); p != nil {
+		return p
+	}
+	// Avoid returning a nil gpio.PinIO in a non-nil interface
+	return nil
+}
+
+// open sets up the pins of the board as inputs detecting edge.
+func (ss *session) open(edge gpio.Edge) error {
+	for _, b := range buttons {
+		name, ok := ss.board.Pins[b]
+		if !ok {
+			continue
+		}
+		p := ss.lookup(name)
+		if p == nil {
+			ss.restore()
+			return fmt.Errorf("unknown pin %q for button %s", name, b)
+		}
+		pull := p.Pull()
+		if err := p.In(ss.board.Pull, edge); err != nil {
+			ss.restore()
+			return err
+		}
+		ss.pins[b] = p
+		ss.pulls[b] = pull
+	}
+	return nil
+}
+
+// start opens the pins and starts watching them. h.mu must be held.
+func (h *hub) start() (*session, error) {
+	ss, err := h.newSession()
+	if err != nil {
+		return nil, err
+	}
+	if err := ss.open(gpio.BothEdges); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ss.cancel = cancel
+	for b, p := range ss.pins {
+		pressed := ss.pressed(p)
+		if pressed {
+			ss.states[b].pressed = true
+			ss.held.Store(ss.held.Load() | uint32(b))
+		}
+		ss.wg.Add(1)
+		go ss.watch(ctx, b, p, pressed)
+	}
+	ss.wg.Add(1)
+	go ss.run(ctx)
+	return ss, nil
+}
+
+// state returns the buttons held right now. While the pins are watched it
+// is the debounced state, otherwise the pins are read once.
+func (h *hub) state() (Button, error) {
+	h.life.Lock()
+	defer h.life.Unlock()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.session != nil {
+		return Button(h.session.held.Load()), nil
+	}
+	ss, err := h.newSession()
+	if err != nil {
+		return 0, err
+	}
+	if err := ss.open(gpio.NoEdge); err != nil {
+		return 0, err
+	}
+	defer ss.restore()
+	var held Button
+	for b, p := range ss.pins {
+		if ss.pressed(p) {
+			held |= b
+		}
+	}
+	return held, nil
+}
+
+// stop ends the session, waits for its goroutines to exit and restores
+// the pins.
+func (ss *session) stop() {
+	ss.cancel()
+	ss.wg.Wait()
+	ss.restore()
+}
+
+// restore turns edge detection off and puts back the pull each pin had.
+func (ss *session) restore() {
+	for b, p := range ss.pins {
+		p.In(ss.pulls[b], gpio.NoEdge)
+	}
+}
+
+// pressed reads whether the button wired to p is pressed.
+func (ss *session) pressed(p gpio.PinIn) bool {
+	return p.Read() == ss.board.Active
+}
+
+// watch sends a transition every time the level of p changes, starting
+// from whether the button was pressed when the pin was set up.
+func (ss *session) watch(ctx context.Context, b Button, p gpio.PinIn, pressed bool) {
+	defer ss.wg.Done()
+	for ctx.Err() == nil {
+		if !p.WaitForEdge(pollInterval) {
+			continue
+		}
+		level := ss.debounce(b, p, pressed)
+		if level == pressed {
+			continue
+		}
+		pressed = level
+		select {
+		case ss.raw <- transition{button: b, pressed: pressed, time: time.Now()}:
+		case <-ctx.Done():
+		}
+	}
+}
+
+// debounce waits for the level of p to settle after an edge and returns
+// whether the button is pressed, given whether it was before the edge.
+// Edges that don't change the level once it settles are counted as bounces.
+func (ss *session) debounce(b Button, p gpio.PinIn, before bool) bool {
+	window := ss.h.options().debounce(b)
+	if window <= 0 {
+		return ss.pressed(p)
+	}
+	time.Sleep(window)
+	edges := uint64(1)
+	for p.WaitForEdge(0) {
+		edges++
+	}
+	pressed := ss.pressed(p)
+	if pressed != before {
+		// One of the edges was the real change
+		edges--
+	}
+	ss.h.bounces[index(b)].Add(edges)
+	return pressed
+}
+
+// run derives events from transitions and timers and sends them to the
+// subscribers.
+func (ss *session) run(ctx context.Context) {
+	defer ss.wg.Done()
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-ss.raw:
+			ss.handle(t)
+		case now := <-timer.C:
+			ss.tick(now)
+		}
+		timer.Reset(ss.nextDeadline())
+	}
+}
+
+func (ss *session) handle(t transition) {
+	opts := ss.h.options()
+	s := ss.states[t.button]
+	if s.pressed == t.pressed {
+		return
+	}
+	s.pressed = t.pressed
+	if t.pressed {
+		ss.held.Store(ss.held.Load() | uint32(t.button))
+		ss.h.emit(Event{Button: t.button, Type: Pressed, Time: t.time})
+		s.double = !s.lastRelease.IsZero() && opts.DoubleClick > 0 && t.time.Sub(s.lastRelease) <= opts.DoubleClick
+		if s.double {
+			ss.h.emit(Event{Button: t.button, Type: DoubleClick, Time: t.time})
+		}
+		s.since = t.time
+		s.lastRelease = time.Time{}
+		s.longSent = false
+		s.nextRepeat = t.time.Add(opts.RepeatDelay)
+		s.interval = opts.RepeatInterval
+		ss.checkChord(t.time, opts)
+		return
+	}
+
+	ss.held.Store(ss.held.Load() &^ uint32(t.button))
+	if ss.chord.buttons&t.button != 0 {
+		ss.chord = chordState{}
+	}
+	ss.h.emit(Event{Button: t.button, Type: Released, Time: t.time, Duration: t.time.Sub(s.since)})
+	// A press right after a double click doesn't make another one
+	if !s.double {
+		s.lastRelease = t.time
+	}
+}
+
+// checkChord sends Chord if the buttons held at now were all pressed within
+// the chord window.
+func (ss *session) checkChord(now time.Time, opts Options) {
+	if opts.ChordWindow <= 0 {
+		return
+	}
+	var held Button
+	for _, b := range buttons {
+		s := ss.states[b]
+		if !s.pressed {
+			continue
+		}
+		if now.Sub(s.since) > opts.ChordWindow {
+			return
+		}
+		held |= b
+	}
+	if bits.OnesCount8(uint8(held)) < 2 {
+		return
+	}
+	ss.chord = chordState{buttons: held, since: now}
+	ss.h.emit(Event{Button: held, Type: Chord, Time: now})
+}
+
+// tick sends the events that are due because buttons are being held.
+func (ss *session) tick(now time.Time) {
+	opts := ss.h.options()
+	for _, b := range buttons {
+		s := ss.states[b]
+		if !s.pressed {
+			continue
+		}
+		held := now.Sub(s.since)
+		if opts.LongPress > 0 && !s.longSent && held >= opts.LongPress {
+			s.longSent = true
+			ss.h.emit(Event{Button: b, Type: LongPress, Time: now, Duration: held})
+		}
+		if opts.RepeatDelay > 0 && opts.RepeatInterval > 0 && !now.Before(s.nextRepeat) {
+			ss.h.emit(Event{Button: b, Type: Repeat, Time: now, Duration: held})
+			s.nextRepeat = s.nextRepeat.Add(s.interval)
+			if s.nextRepeat.Before(now) {
+				s.nextRepeat = now.Add(s.interval)
+			}
+			s.interval = opts.nextRepeatInterval(s.interval)
+		}
+	}
+	c := &ss.chord
+	if c.buttons != 0 && opts.LongPress > 0 && !c.longSent && now.Sub(c.since) >= opts.LongPress {
+		c.longSent = true
+		ss.h.emit(Event{Button: c.buttons, Type: ChordLongPress, Time: now, Duration: now.Sub(c.since)})
+	}
+}
+
+// nextDeadline returns how long until the next call to tick is needed.
+func (ss *session) nextDeadline() time.Duration {
+	opts := ss.h.options()
+	next := time.Hour
+	now := time.Now()
+	for _, b := range buttons {
+		s := ss.states[b]
+		if !s.pressed {
+			continue
+		}
+		if opts.LongPress > 0 && !s.longSent {
+			next = min(next, s.since.Add(opts.LongPress).Sub(now))
+		}
+		if opts.RepeatDelay > 0 && opts.RepeatInterval > 0 {
+			next = min(next, s.nextRepeat.Sub(now))
+		}
+	}
+	if c := ss.chord; c.buttons != 0 && opts.LongPress > 0 && !c.longSent {
+		next = min(next, c.since.Add(opts.LongPress).Sub(now))
+	}
+	return max(next, 0)
+}
+
+// index returns the position of the lowest button in b.
+func index(b Button) int {
+	return bits.TrailingZeros8(uint8(b))
+}
+
+// emit sends ev to every subscriber.
+func (h *hub) emit(ev Event) {
+	h.mu.Lock()
+	subs := make([]*subscriber, 0, len(h.subs))
+	for s := range h.subs {
+		subs = append(subs, s)
+	}
+	h.mu.Unlock()
+	for _, s := range subs {
+		select {
+		case s.in <- ev:
+		case <-s.done:
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package buttons
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// record is an event as written by Record, one JSON object per line.
+type record struct {
+	Time     time.Time `json:"time"`
+	Button   string    `json:"button"`
+	Type     string    `json:"type"`
+	Duration string    `json:"duration,omitempty"`
+}
+
+// Record writes every event to w as JSON lines until ctx is done:
+//
+//	{"time":"2024-05-01T10:00:00.5+02:00","button":"B","type":"Released","duration":"120ms"}
+func Record(ctx context.Context, w io.Writer) error {
+	// Stop listening when returning early on a write error
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, err := Events(ctx)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	for ev := range events {
+		rec := record{Time: ev.Time, Button: ev.Button.String(), Type: ev.Type.String()}
+		if ev.Duration > 0 {
+			rec.Duration = ev.Duration.String()
+		}
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Replay reads events written by Record and plays the presses and releases
+// with the same timing, as if the buttons were pressed. The other events
+// are derived from them again, with the current options. The buttons are
+// watched while replaying, so real presses are mixed in.
+func Replay(ctx context.Context, r io.Reader) error {
+	var recs []record
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var rec record
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return fmt.Errorf("buttons: line %d: %w", n, err)
+		}
+		recs = append(recs, rec)
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+
+	// Keep the buttons watched, dropping the events
+	s, err := std.subscribe()
+	if err != nil {
+		return err
+	}
+	defer std.unsubscribe(s)
+	go func() {
+		for range s.ch {
+		}
+	}()
+
+	var last time.Time
+	for _, rec := range recs {
+		if rec.Type != Pressed.String() && rec.Type != Released.String() {
+			continue
+		}
+		b, err := ParseButton(rec.Button)
+		if err != nil {
+			return err
+		}
+		if b&(b-1) != 0 {
+			return fmt.Errorf("buttons: %s of several buttons: %s", rec.Type, b)
+		}
+		if !last.IsZero() {
+			if err := sleep(ctx, rec.Time.Sub(last)); err != nil {
+				return err
+			}
+		}
+		last = rec.Time
+		t := transition{button: b, pressed: rec.Type == Pressed.String(), time: time.Now()}
+		if err := std.inject(ctx, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,39 @@
+package buttons_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rubiojr/go-pirateaudio/buttons"
+)
+
+var errWrite = errors.New("write failed")
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errWrite }
+
+func TestRecordWriteError(t *testing.T) {
+	s := newSim(t, buttons.DefaultOpts)
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- buttons.Record(context.Background(), failingWriter{})
+	}()
+	waitWatched(t, s, buttons.All)
+	s.Click(buttons.A)
+
+	select {
+	case err := <-errc:
+		if !errors.Is(err, errWrite) {
+			t.Fatalf("got error %v, want %v", err, errWrite)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Record didn't return after a write error")
+	}
+	// Record was the only listener, so the pins are restored
+	waitWatched(t, s, 0)
+}
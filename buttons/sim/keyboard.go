@@ -0,0 +1,47 @@
+package sim
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"unicode"
+
+	"github.com/rubiojr/go-pirateaudio/buttons"
+)
+
+// keys maps the lower case keys read by Keyboard to buttons.
+var keys = map[rune]buttons.Button{
+	'a': buttons.A,
+	'b': buttons.B,
+	'x': buttons.X,
+	'y': buttons.Y,
+}
+
+// Keyboard presses buttons from the keys read from r until EOF: a, b, x
+// and y click the button, and A, B, X and Y press it or, if it is held,
+// release it. Other keys are ignored.
+//
+// Terminals send keys once Enter is pressed. For single keypresses, put the
+// terminal in non-canonical mode first, for instance with stty -icanon.
+func (s *Sim) Keyboard(r io.Reader) error {
+	br := bufio.NewReader(r)
+	for {
+		c, _, err := br.ReadRune()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		b, ok := keys[unicode.ToLower(c)]
+		switch {
+		case !ok:
+		case unicode.IsLower(c):
+			s.Click(b)
+		case s.Held()&b != 0:
+			s.Release(b)
+		default:
+			s.Press(b)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package sim
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/rubiojr/go-pirateaudio/buttons"
+)
+
+// step is a parsed script line.
+type step struct {
+	op      string
+	buttons buttons.Button
+	d       time.Duration
+}
+
+// Run plays a script read from r. Each line is one of
+//
+//	press <buttons>
+//	release <buttons>
+//	click <buttons>
+//	hold <buttons> <duration>
+//	wait <duration>
+//
+// where buttons are names joined with +, such as A or A+Y, and durations
+// are in time.ParseDuration format. Empty lines and lines starting with #
+// are ignored. The whole script is checked before it is played. Run
+// returns when the script ends or ctx is done.
+func (s *Sim) Run(ctx context.Context, r io.Reader) error {
+	steps, err := parseScript(r)
+	if err != nil {
+		return err
+	}
+	for _, st := range steps {
+		switch st.op {
+		case "press":
+			s.Press(st.buttons)
+		case "release":
+			s.Release(st.buttons)
+		case "click":
+			s.Press(st.buttons)
+			err = sleep(ctx, ClickDuration)
+			s.Release(st.buttons)
+		case "hold":
+			s.Press(st.buttons)
+			err = sleep(ctx, st.d)
+			s.Release(st.buttons)
+		case "wait":
+			err = sleep(ctx, st.d)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func parseScript(r io.Reader) ([]step, error) {
+	var steps []step
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		st, err := parseStep(strings.Fields(line))
+		if err != nil {
+			return nil, fmt.Errorf("sim: line %d: %w", n, err)
+		}
+		steps = append(steps, st)
+	}
+	return steps, sc.Err()
+}
+
+func parseStep(f []string) (step, error) {
+	st := step{op: f[0]}
+	var err error
+	switch st.op {
+	case "press", "release", "click":
+		if len(f) != 2 {
+			return st, fmt.Errorf("usage: %s <buttons>", st.op)
+		}
+		st.buttons, err = buttons.ParseButton(f[1])
+	case "hold":
+		if len(f) != 3 {
+			return st, fmt.Errorf("usage: hold <buttons> <duration>")
+		}
+		if st.buttons, err = buttons.ParseButton(f[1]); err == nil {
+			st.d, err = time.ParseDuration(f[2])
+		}
+	case "wait":
+		if len(f) != 2 {
+			return st, fmt.Errorf("usage: wait <duration>")
+		}
+		st.d, err = time.ParseDuration(f[1])
+	default:
+		err = fmt.Errorf("unknown command %q", st.op)
+	}
+	return st, err
+}
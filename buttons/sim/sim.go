@@ -0,0 +1,222 @@
+// Package sim simulates the buttons, so code using the buttons package can
+// run on machines without the hardware. Buttons are pressed from code, from
+// a terminal with Keyboard or from a script with Run:
+//
+//	s := sim.New()
+//	opts := buttons.DefaultOpts
+//	opts.Board = s.Board()
+//	if err := buttons.Configure(opts); err != nil {
+//		...
+//	}
+//	s.Click(buttons.A)
+//
+// The simulated pins don't need periph's host drivers.
+package sim
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rubiojr/go-pirateaudio/buttons"
+	"periph.io/x/conn/v3/gpio"
+)
+
+// maxEdges is how many edges a pin queues until they are waited for, more
+// are dropped.
+const maxEdges = 64
+
+// ClickDuration is how long Click holds the buttons down. It is longer than
+// the default debouncing window, so clicks are not filtered out.
+const ClickDuration = 50 * time.Millisecond
+
+// names of the simulated pins, indexed by button.
+var names = map[buttons.Button]string{
+	buttons.A: "SIM_A",
+	buttons.B: "SIM_B",
+	buttons.X: "SIM_X",
+	buttons.Y: "SIM_Y",
+}
+
+// Sim is a set of simulated buttons.
+type Sim struct {
+	pins map[buttons.Button]*pin
+}
+
+// New returns simulated buttons, all released, with floating pins.
+func New() *Sim {
+	s := &Sim{pins: map[buttons.Button]*pin{}}
+	for b, n := range names {
+		s.pins[b] = &pin{name: n, level: gpio.High, pull: gpio.Float, edges: make(chan struct{}, maxEdges)}
+	}
+	return s
+}
+
+// Board returns the board profile to set in buttons.Options to read the
+// simulated buttons.
+func (s *Sim) Board() buttons.Board {
+	pins := map[buttons.Button]string{}
+	for b, n := range names {
+		pins[b] = n
+	}
+	return buttons.Board{
+		Name:   "Simulator",
+		Pins:   pins,
+		Active: gpio.Low,
+		Pull:   gpio.PullUp,
+		Lookup: s.lookup,
+	}
+}
+
+func (s *Sim) lookup(name string) gpio.PinIn {
+	for _, p := range s.pins {
+		if p.name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// Press pushes down the buttons in b.
+func (s *Sim) Press(b buttons.Button) {
+	s.set(b, gpio.Low)
+}
+
+// Release lets go of the buttons in b.
+func (s *Sim) Release(b buttons.Button) {
+	s.set(b, gpio.High)
+}
+
+// Click presses the buttons in b and releases them after ClickDuration.
+func (s *Sim) Click(b buttons.Button) {
+	s.Hold(b, ClickDuration)
+}
+
+// Hold presses the buttons in b and releases them after d.
+func (s *Sim) Hold(b buttons.Button, d time.Duration) {
+	s.Press(b)
+	time.Sleep(d)
+	s.Release(b)
+}
+
+// Held returns the buttons currently pressed.
+func (s *Sim) Held() buttons.Button {
+	var held buttons.Button
+	for b, p := range s.pins {
+		if p.Read() == gpio.Low {
+			held |= b
+		}
+	}
+	return held
+}
+
+// Watched returns the buttons whose pins are set up to detect edges, that
+// is, the buttons being watched.
+func (s *Sim) Watched() buttons.Button {
+	var watched buttons.Button
+	for b, p := range s.pins {
+		p.mu.Lock()
+		if p.edge != gpio.NoEdge {
+			watched |= b
+		}
+		p.mu.Unlock()
+	}
+	return watched
+}
+
+func (s *Sim) set(b buttons.Button, l gpio.Level) {
+	for bb, p := range s.pins {
+		if b&bb != 0 {
+			p.set(l)
+		}
+	}
+}
+
+// pin is a simulated button pin, low while the button is pressed.
+type pin struct {
+	name string
+
+	mu    sync.Mutex
+	level gpio.Level
+	pull  gpio.Pull
+	edge  gpio.Edge
+	// Holds a value for each pending edge
+	edges chan struct{}
+}
+
+func (p *pin) set(l gpio.Level) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.level == l {
+		return
+	}
+	p.level = l
+	if p.edge == gpio.NoEdge ||
+		(p.edge == gpio.RisingEdge && l == gpio.Low) ||
+		(p.edge == gpio.FallingEdge && l == gpio.High) {
+		return
+	}
+	select {
+	case p.edges <- struct{}{}:
+	default:
+	}
+}
+
+func (p *pin) String() string   { return p.name }
+func (p *pin) Halt() error      { return nil }
+func (p *pin) Name() string     { return p.name }
+func (p *pin) Number() int      { return -1 }
+func (p *pin) Function() string { return "In/" + p.Read().String() }
+
+// In implements gpio.PinIn. The level doesn't depend on the pull, so a
+// button held while the pin is set up stays pressed.
+func (p *pin) In(pull gpio.Pull, edge gpio.Edge) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pull != gpio.PullNoChange {
+		p.pull = pull
+	}
+	p.edge = edge
+	for len(p.edges) > 0 {
+		<-p.edges
+	}
+	return nil
+}
+
+func (p *pin) Read() gpio.Level {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.level
+}
+
+func (p *pin) WaitForEdge(timeout time.Duration) bool {
+	switch {
+	case timeout < 0:
+		<-p.edges
+		return true
+	case timeout == 0:
+		select {
+		case <-p.edges:
+			return true
+		default:
+			return false
+		}
+	}
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case <-p.edges:
+		return true
+	case <-t.C:
+		return false
+	}
+}
+
+func (p *pin) Pull() gpio.Pull {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pull
+}
+
+func (p *pin) DefaultPull() gpio.Pull {
+	return gpio.PullUp
+}
@@ -0,0 +1,29 @@
+package buttons
+
+import "context"
+
+// State returns the buttons held right now. It can be called at any time,
+// such as at startup to check whether a button is already down.
+func State() (Button, error) {
+	return std.state()
+}
+
+// Wait blocks until one of the buttons in mask is pressed and returns it,
+// or until ctx is done.
+func Wait(ctx context.Context, mask Button) (Button, error) {
+	s, err := std.subscribe()
+	if err != nil {
+		return 0, err
+	}
+	defer std.unsubscribe(s)
+	for {
+		select {
+		case ev := <-s.ch:
+			if ev.Type == Pressed && ev.Button&mask != 0 {
+				return ev.Button, nil
+			}
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+}
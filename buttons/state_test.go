@@ -0,0 +1,71 @@
+package buttons_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rubiojr/go-pirateaudio/buttons"
+	"github.com/rubiojr/go-pirateaudio/buttons/sim"
+	"periph.io/x/conn/v3/gpio"
+)
+
+func TestState(t *testing.T) {
+	s := newSim(t, buttons.DefaultOpts)
+
+	// Held before anything watches the buttons
+	s.Press(buttons.X)
+	if b, err := buttons.State(); err != nil || b != buttons.X {
+		t.Fatalf("State() = %s, %v, want X", b, err)
+	}
+	if w := s.Watched(); w != 0 {
+		t.Errorf("watching %s after State, want none", w)
+	}
+	checkPulls(t, s, gpio.Float)
+
+	// Still held when the buttons start being watched
+	events := listen(t)
+	if b, err := buttons.State(); err != nil || b != buttons.X {
+		t.Fatalf("State() while watching = %s, %v, want X", b, err)
+	}
+	s.Press(buttons.A)
+	expect(t, events, "A Pressed")
+	if b, err := buttons.State(); err != nil || b != buttons.A|buttons.X {
+		t.Fatalf("State() = %s, %v, want A+X", b, err)
+	}
+	s.Release(buttons.A | buttons.X)
+	waitFor(t, "the buttons to be released", func() bool {
+		b, err := buttons.State()
+		return err == nil && b == 0
+	})
+}
+
+func TestWait(t *testing.T) {
+	s := newSim(t, buttons.DefaultOpts)
+
+	go func() {
+		for s.Watched() != buttons.All {
+			time.Sleep(5 * time.Millisecond)
+		}
+		s.Click(buttons.B)
+		time.Sleep(sim.ClickDuration)
+		s.Click(buttons.Y)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	b, err := buttons.Wait(ctx, buttons.X|buttons.Y)
+	if err != nil || b != buttons.Y {
+		t.Fatalf("Wait() = %s, %v, want Y", b, err)
+	}
+	waitWatched(t, s, 0)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := buttons.Wait(ctx, buttons.All); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait() with nothing pressed returned %v, want %v", err, context.DeadlineExceeded)
+	}
+	if w := s.Watched(); w != 0 {
+		t.Errorf("watching %s after Wait returned, want none", w)
+	}
+}
@@ -0,0 +1,156 @@
+// Package uinput makes the buttons act as a keyboard, so programs such as
+// media players react to them without any integration.
+//
+// Open creates a virtual keyboard through /dev/uinput on Linux:
+//
+//	kbd, err := uinput.Open(uinput.DefaultKeys)
+//	if err != nil {
+//		...
+//	}
+//	defer kbd.Close()
+//
+// New writes the input events to any io.Writer instead, such as a buffer
+// in tests.
+package uinput
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rubiojr/go-pirateaudio/buttons"
+)
+
+// Event types and codes, from linux/input-event-codes.h.
+const (
+	EV_SYN     = 0x00
+	EV_KEY     = 0x01
+	SYN_REPORT = 0
+
+	KEY_ESC          = 1
+	KEY_ENTER        = 28
+	KEY_SPACE        = 57
+	KEY_UP           = 103
+	KEY_LEFT         = 105
+	KEY_RIGHT        = 106
+	KEY_DOWN         = 108
+	KEY_MUTE         = 113
+	KEY_VOLUMEDOWN   = 114
+	KEY_VOLUMEUP     = 115
+	KEY_NEXTSONG     = 163
+	KEY_PLAYPAUSE    = 164
+	KEY_PREVIOUSSONG = 165
+	KEY_STOPCD       = 166
+)
+
+// Key values of EV_KEY events.
+const (
+	released = 0
+	pressed  = 1
+	repeated = 2
+)
+
+// DefaultKeys follows the labels of the Pirate Audio buttons.
+var DefaultKeys = map[buttons.Button]uint16{
+	buttons.A: KEY_PLAYPAUSE,
+	buttons.B: KEY_VOLUMEDOWN,
+	buttons.X: KEY_NEXTSONG,
+	buttons.Y: KEY_VOLUMEUP,
+}
+
+// InputEvent is the struct input_event read and written by the kernel
+// input devices. Its layout depends on the platform, through Timeval.
+type InputEvent struct {
+	Time  syscall.Timeval
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+// Keyboard sends key events for the buttons, pressed, released and
+// repeated, to a writer.
+type Keyboard struct {
+	w      io.Writer
+	keys   map[buttons.Button]uint16
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error // first write error, set before done is closed
+	once   sync.Once
+}
+
+// New starts sending the key events of the buttons in keys to w, which
+// receives the input_event structs of each key change, followed by a
+// SYN_REPORT, in a single write. If w is an io.Closer, Close closes it.
+func New(w io.Writer, keys map[buttons.Button]uint16) (*Keyboard, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := buttons.Events(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	k := &Keyboard{
+		w:      w,
+		keys:   keys,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go k.run(events)
+	return k, nil
+}
+
+func (k *Keyboard) run(events <-chan buttons.Event) {
+	defer close(k.done)
+	for ev := range events {
+		if k.err != nil {
+			continue
+		}
+		code, ok := k.keys[ev.Button]
+		if !ok {
+			continue
+		}
+		switch ev.Type {
+		case buttons.Pressed:
+			k.err = k.send(ev.Time, code, pressed)
+		case buttons.Released:
+			k.err = k.send(ev.Time, code, released)
+		case buttons.Repeat:
+			k.err = k.send(ev.Time, code, repeated)
+		}
+	}
+}
+
+// send writes a key event and the report closing it.
+func (k *Keyboard) send(t time.Time, code uint16, value int32) error {
+	tv := syscall.NsecToTimeval(t.UnixNano())
+	var buf bytes.Buffer
+	for _, ev := range []InputEvent{
+		{Time: tv, Type: EV_KEY, Code: code, Value: value},
+		{Time: tv, Type: EV_SYN, Code: SYN_REPORT},
+	} {
+		if err := binary.Write(&buf, binary.NativeEndian, ev); err != nil {
+			return err
+		}
+	}
+	_, err := k.w.Write(buf.Bytes())
+	return err
+}
+
+// Close stops sending events and closes the writer if it is an io.Closer.
+// It returns the first error met writing events.
+func (k *Keyboard) Close() error {
+	var err error
+	k.once.Do(func() {
+		k.cancel()
+		<-k.done
+		err = k.err
+		if c, ok := k.w.(io.Closer); ok {
+			err = errors.Join(err, c.Close())
+		}
+	})
+	return err
+}
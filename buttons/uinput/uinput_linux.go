@@ -0,0 +1,97 @@
+package uinput
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"github.com/rubiojr/go-pirateaudio/buttons"
+)
+
+// Path is the uinput device Open writes to.
+const Path = "/dev/uinput"
+
+// ioctl requests, from linux/uinput.h.
+const (
+	uiDevCreate  = 0x5501
+	uiDevDestroy = 0x5502
+	uiDevSetup   = 0x405c5503
+	uiSetEvBit   = 0x40045564
+	uiSetKeyBit  = 0x40045565
+)
+
+// busVirtual is BUS_VIRTUAL from linux/input.h.
+const busVirtual = 0x06
+
+// uinputSetup is struct uinput_setup, 92 bytes.
+type uinputSetup struct {
+	BusType      uint16
+	Vendor       uint16
+	Product      uint16
+	Version      uint16
+	Name         [80]byte
+	FFEffectsMax uint32
+}
+
+// Open creates a virtual keyboard named "Pirate Audio buttons" able to send
+// the keys in keys, and starts sending them as the buttons are used.
+// Writing to /dev/uinput usually requires root or membership of the input
+// group.
+func Open(keys map[buttons.Button]uint16) (*Keyboard, error) {
+	f, err := os.OpenFile(Path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+	d := &device{f: f}
+	if err := d.setup(keys); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("uinput: %w", err)
+	}
+	k, err := New(d, keys)
+	if err != nil {
+		d.Close()
+		return nil, err
+	}
+	return k, nil
+}
+
+// device is a virtual input device created through uinput.
+type device struct {
+	f *os.File
+}
+
+func (d *device) setup(keys map[buttons.Button]uint16) error {
+	if err := d.ioctl(uiSetEvBit, EV_KEY); err != nil {
+		return err
+	}
+	for _, code := range keys {
+		if err := d.ioctl(uiSetKeyBit, uintptr(code)); err != nil {
+			return err
+		}
+	}
+	s := uinputSetup{BusType: busVirtual, Vendor: 0x1209, Product: 0x0001, Version: 1}
+	copy(s.Name[:], "Pirate Audio buttons")
+	if err := d.ioctl(uiDevSetup, uintptr(unsafe.Pointer(&s))); err != nil {
+		return err
+	}
+	return d.ioctl(uiDevCreate, 0)
+}
+
+func (d *device) ioctl(req, arg uintptr) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, d.f.Fd(), req, arg)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func (d *device) Write(b []byte) (int, error) {
+	return d.f.Write(b)
+}
+
+// Close removes the virtual device.
+func (d *device) Close() error {
+	return errors.Join(d.ioctl(uiDevDestroy, 0), d.f.Close())
+}
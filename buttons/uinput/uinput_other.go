@@ -0,0 +1,14 @@
+//go:build !linux
+
+package uinput
+
+import (
+	"errors"
+
+	"github.com/rubiojr/go-pirateaudio/buttons"
+)
+
+// Open creates a virtual keyboard. It is only supported on Linux.
+func Open(keys map[buttons.Button]uint16) (*Keyboard, error) {
+	return nil, errors.New("uinput: only supported on Linux")
+}
@@ -0,0 +1,159 @@
+package uinput_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rubiojr/go-pirateaudio/buttons"
+	"github.com/rubiojr/go-pirateaudio/buttons/sim"
+	"github.com/rubiojr/go-pirateaudio/buttons/uinput"
+)
+
+// buffer is a bytes.Buffer that can be read while the keyboard writes.
+type buffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *buffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *buffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Len()
+}
+
+// events decodes the input_event structs written to b.
+func (b *buffer) events(t *testing.T) []uinput.InputEvent {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var evs []uinput.InputEvent
+	r := bytes.NewReader(b.buf.Bytes())
+	for {
+		var ev uinput.InputEvent
+		err := binary.Read(r, binary.NativeEndian, &ev)
+		if errors.Is(err, io.EOF) {
+			return evs
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		evs = append(evs, ev)
+	}
+}
+
+// newKeyboard returns simulated buttons and a keyboard writing the events
+// of keys to a buffer.
+func newKeyboard(t *testing.T, keys map[buttons.Button]uint16) (*sim.Sim, *uinput.Keyboard, *buffer) {
+	t.Helper()
+	s := sim.New()
+	opts := buttons.DefaultOpts
+	opts.Board = s.Board()
+	if err := buttons.Configure(opts); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		buttons.Configure(buttons.DefaultOpts)
+	})
+
+	buf := &buffer{}
+	kbd, err := uinput.New(buf, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, kbd, buf
+}
+
+// waitEvents waits until n input_event structs have been written to buf.
+func waitEvents(t *testing.T, buf *buffer, n int) {
+	t.Helper()
+	size := binary.Size(uinput.InputEvent{})
+	deadline := time.Now().Add(2 * time.Second)
+	for buf.Len() < n*size {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d bytes, want %d input events", buf.Len(), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestClick(t *testing.T) {
+	s, kbd, buf := newKeyboard(t, map[buttons.Button]uint16{buttons.A: uinput.KEY_ENTER})
+
+	s.Click(buttons.X) // not mapped
+	s.Click(buttons.A)
+	waitEvents(t, buf, 4)
+	if err := kbd.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []uinput.InputEvent{
+		{Type: uinput.EV_KEY, Code: uinput.KEY_ENTER, Value: 1},
+		{Type: uinput.EV_SYN, Code: uinput.SYN_REPORT},
+		{Type: uinput.EV_KEY, Code: uinput.KEY_ENTER, Value: 0},
+		{Type: uinput.EV_SYN, Code: uinput.SYN_REPORT},
+	}
+	got := buf.events(t)
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(got), len(want), got)
+	}
+	for i, ev := range got {
+		if ev.Time.Sec == 0 && ev.Time.Usec == 0 {
+			t.Errorf("event %d has no time", i)
+		}
+		ev.Time = want[i].Time
+		if ev != want[i] {
+			t.Errorf("event %d: got %+v, want %+v", i, ev, want[i])
+		}
+	}
+	// Each report shares the time of its key event
+	if got[0].Time != got[1].Time || got[2].Time != got[3].Time {
+		t.Errorf("SYN_REPORT times differ from their key events: %+v", got)
+	}
+}
+
+func TestRepeat(t *testing.T) {
+	s, kbd, buf := newKeyboard(t, uinput.DefaultKeys)
+
+	s.Hold(buttons.Y, buttons.DefaultOpts.RepeatDelay+2*buttons.DefaultOpts.RepeatInterval)
+	// Down, at least one repeat and up, each followed by a report
+	waitEvents(t, buf, 6)
+	deadline := time.Now().Add(2 * time.Second)
+	for last := buf.events(t); last[len(last)-2].Value != 0; last = buf.events(t) {
+		if time.Now().After(deadline) {
+			t.Fatal("key up not received")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := kbd.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var values []int32
+	for _, ev := range buf.events(t) {
+		if ev.Type == uinput.EV_KEY {
+			if ev.Code != uinput.KEY_VOLUMEUP {
+				t.Errorf("got key %d, want KEY_VOLUMEUP", ev.Code)
+			}
+			values = append(values, ev.Value)
+		}
+	}
+	if len(values) < 3 || values[0] != 1 || values[len(values)-1] != 0 {
+		t.Fatalf("got key values %v, want down, repeats and up", values)
+	}
+	for _, v := range values[1 : len(values)-1] {
+		if v != 2 {
+			t.Errorf("got key values %v, want only repeats between down and up", values)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package display
+
+import "image"
+
+// MergeStrategy decides how dirty regions are combined into the address
+// windows sent to the display on Flush.
+type MergeStrategy uint8
+
+const (
+	// MERGE_COST merges neighbouring dirty regions whenever sending them as a
+	// single window costs fewer bytes than sending them apart.
+	MERGE_COST MergeStrategy = 0
+	// MERGE_TILES sends every dirty tile as its own window.
+	MERGE_TILES MergeStrategy = 1
+	// MERGE_BOUNDS sends a single window covering every change.
+	MERGE_BOUNDS MergeStrategy = 2
+)
+
+// tileSize is the side, in pixels, of the cells dirty regions are tracked in.
+const tileSize = 16
+
+// windowOverhead is roughly the number of bytes needed to set up an address
+// window: CASET, RASET and RAMWR plus their parameters.
+const windowOverhead = 11
+
+// Stats holds counters about the data sent to the display by Flush.
+type Stats struct {
+	Flushes    uint64 // number of Flush calls that sent something
+	Windows    uint64 // number of address windows written
+	BytesSent  uint64 // pixel and window setup bytes sent
+	BytesSaved uint64 // bytes not sent compared to a full frame per flush
+}
+
+// dirtyTracker keeps the changed area of each tile of the display.
+type dirtyTracker struct {
+	bounds image.Rectangle
+	cols   int
+	rows   int
+	tiles  []image.Rectangle
+}
+
+func newDirtyTracker(bounds image.Rectangle) *dirtyTracker {
+	cols := (bounds.Dx() + tileSize - 1) / tileSize
+	rows := (bounds.Dy() + tileSize - 1) / tileSize
+	return &dirtyTracker{
+		bounds: bounds,
+		cols:   cols,
+		rows:   rows,
+		tiles:  make([]image.Rectangle, cols*rows),
+	}
+}
+
+// mark records r as changed.
+func (t *dirtyTracker) mark(r image.Rectangle) {
+	r = r.Intersect(t.bounds)
+	if r.Empty() {
+		return
+	}
+	for ty := r.Min.Y / tileSize; ty <= (r.Max.Y-1)/tileSize; ty++ {
+		for tx := r.Min.X / tileSize; tx <= (r.Max.X-1)/tileSize; tx++ {
+			tile := image.Rect(tx*tileSize, ty*tileSize, (tx+1)*tileSize, (ty+1)*tileSize)
+			i := ty*t.cols + tx
+			t.tiles[i] = t.tiles[i].Union(r.Intersect(tile))
+		}
+	}
+}
+
+func (t *dirtyTracker) reset() {
+	clear(t.tiles)
+}
+
+// rects returns the windows covering every change, combined according to s.
+func (t *dirtyTracker) rects(s MergeStrategy) []image.Rectangle {
+	var rs []image.Rectangle
+	for _, r := range t.tiles {
+		if !r.Empty() {
+			rs = append(rs, r)
+		}
+	}
+	if len(rs) == 0 {
+		return nil
+	}
+
+	switch s {
+	case MERGE_TILES:
+		return rs
+	case MERGE_BOUNDS:
+		b := rs[0]
+		for _, r := range rs[1:] {
+			b = b.Union(r)
+		}
+		return []image.Rectangle{b}
+	}
+	return mergeByCost(rs)
+}
+
+// windowCost returns the bytes needed to send r as one window.
+func windowCost(r image.Rectangle) int {
+	return r.Dx()*r.Dy()*2 + windowOverhead
+}
+
+// mergeByCost greedily joins rectangles while doing so reduces the number
+// of bytes to send. rs must be in tile order (rows top to bottom, left to
+// right within a row).
+func mergeByCost(rs []image.Rectangle) []image.Rectangle {
+	// Join tiles along each tile row first.
+	var spans []image.Rectangle
+	for _, r := range rs {
+		if n := len(spans) - 1; n >= 0 && spans[n].Min.Y/tileSize == r.Min.Y/tileSize {
+			u := spans[n].Union(r)
+			if windowCost(u) <= windowCost(spans[n])+windowCost(r) {
+				spans[n] = u
+				continue
+			}
+		}
+		spans = append(spans, r)
+	}
+
+	// Then grow the resulting spans downwards.
+	var merged []image.Rectangle
+next:
+	for _, r := range spans {
+		for i, m := range merged {
+			u := m.Union(r)
+			if windowCost(u) <= windowCost(m)+windowCost(r) {
+				merged[i] = u
+				continue next
+			}
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
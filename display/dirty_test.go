@@ -0,0 +1,83 @@
+package display
+
+import (
+	"image"
+	"slices"
+	"testing"
+)
+
+func TestDirtyTracker(t *testing.T) {
+	dt := newDirtyTracker(image.Rect(0, 0, 40, 40))
+	if rs := dt.rects(MERGE_TILES); rs != nil {
+		t.Fatalf("clean tracker has windows %v", rs)
+	}
+
+	// Split along the tiles and clipped to the bounds
+	dt.mark(image.Rect(10, 12, 20, 14))
+	dt.mark(image.Rect(35, 35, 50, 50))
+	dt.mark(image.Rect(-5, -5, 0, 0))
+	want := []image.Rectangle{
+		image.Rect(10, 12, 16, 14),
+		image.Rect(16, 12, 20, 14),
+		image.Rect(35, 35, 40, 40),
+	}
+	if rs := dt.rects(MERGE_TILES); !slices.Equal(rs, want) {
+		t.Errorf("windows %v, want %v", rs, want)
+	}
+	if rs := dt.rects(MERGE_BOUNDS); !slices.Equal(rs, []image.Rectangle{image.Rect(10, 12, 40, 40)}) {
+		t.Errorf("bounding window %v, want (10,12)-(40,40)", rs)
+	}
+
+	// Grown within a tile
+	dt.mark(image.Rect(2, 2, 4, 4))
+	dt.mark(image.Rect(6, 8, 8, 10))
+	if rs := dt.rects(MERGE_TILES); rs[0] != image.Rect(2, 2, 16, 14) {
+		t.Errorf("first tile window %v, want (2,2)-(16,14)", rs[0])
+	}
+
+	dt.reset()
+	if rs := dt.rects(MERGE_COST); rs != nil {
+		t.Errorf("windows after reset %v, want none", rs)
+	}
+}
+
+func TestMergeByCost(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		rs   []image.Rectangle
+		want []image.Rectangle
+	}{
+		{
+			"full neighbours in a row",
+			[]image.Rectangle{image.Rect(0, 0, 16, 16), image.Rect(16, 0, 32, 16)},
+			[]image.Rectangle{image.Rect(0, 0, 32, 16)},
+		},
+		{
+			"small changes far apart",
+			[]image.Rectangle{image.Rect(0, 0, 1, 1), image.Rect(200, 0, 201, 1)},
+			[]image.Rectangle{image.Rect(0, 0, 1, 1), image.Rect(200, 0, 201, 1)},
+		},
+		{
+			"rows grown downwards",
+			[]image.Rectangle{image.Rect(0, 0, 32, 16), image.Rect(0, 16, 32, 32)},
+			[]image.Rectangle{image.Rect(0, 0, 32, 32)},
+		},
+		{
+			"rows apart",
+			[]image.Rectangle{image.Rect(0, 0, 16, 1), image.Rect(0, 200, 16, 201)},
+			[]image.Rectangle{image.Rect(0, 0, 16, 1), image.Rect(0, 200, 16, 201)},
+		},
+		{
+			// Joining costs the gap, less than the overhead of a window
+			"small gap",
+			[]image.Rectangle{image.Rect(0, 0, 16, 1), image.Rect(18, 0, 32, 1)},
+			[]image.Rectangle{image.Rect(0, 0, 32, 1)},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := mergeByCost(tc.rs); !slices.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
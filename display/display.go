@@ -1,15 +1,18 @@
 package display
 
 import (
+	"errors"
+	"fmt"
 	"image"
 	"image/color"
 	"io"
-	"log"
 	"sync"
 
 	"github.com/rubiojr/go-pirateaudio/st7789"
 	"periph.io/x/conn/v3/driver/driverreg"
+	"periph.io/x/conn/v3/gpio"
 	"periph.io/x/conn/v3/gpio/gpioreg"
+	"periph.io/x/conn/v3/physic"
 	"periph.io/x/conn/v3/spi"
 	"periph.io/x/conn/v3/spi/spireg"
 	"periph.io/x/host/v3"
@@ -24,71 +27,400 @@ const (
 	ROTATION_270 Rotation = 3
 )
 
-var once sync.Once
-var display *Display
+type PowerMode uint8
+
+const (
+	POWER_NORMAL       PowerMode = 0 // full display, 65K colours
+	POWER_PARTIAL      PowerMode = 1 // only the rows set with SetPartialArea are shown
+	POWER_IDLE         PowerMode = 2 // full display, 8 colours
+	POWER_PARTIAL_IDLE PowerMode = 3 // partial and idle combined
+	POWER_SLEEP        PowerMode = 4 // display and backlight off, memory retained
+)
 
 type Display struct {
-	port spi.PortCloser
-	dev  *st7789.Device
+	port  spi.PortCloser
+	pins  []gpio.PinIO
+	dev   *st7789.Device
+	dirty *dirtyTracker
+	merge MergeStrategy
+	stats Stats
 }
 
-func init() {
-	if _, err := host.Init(); err != nil {
-		log.Fatal(err)
+// Errors returned by the display, to be matched with errors.Is.
+var (
+	ErrDecode      = st7789.ErrDecode
+	ErrBus         = st7789.ErrBus
+	ErrOutOfBounds = st7789.ErrOutOfBounds
+	ErrUnsupported = st7789.ErrUnsupported
+)
+
+// Options defines the wiring and bus settings of the display. Zero fields
+// take their value from DefaultOpts, but for Backlight and Reset, where
+// empty means not connected.
+type Options struct {
+	SPIPort   string           // SPI port name, as known to periph's spireg
+	DC        string           // data/command pin name
+	Backlight string           // backlight pin name, empty if not connected
+	Reset     string           // reset pin name, empty if not connected
+	Speed     physic.Frequency // SPI clock
+	Mode      spi.Mode         // SPI mode
+	Width     int16
+	Height    int16
+	// ColumnOffset and RowOffset are where the panel starts in the
+	// controller memory, see st7789.Opts.
+	ColumnOffset int16
+	RowOffset    int16
+	// SoftwareRotation rotates pixels in the driver, for panels where the
+	// controller's rotation settings give the wrong orientation.
+	SoftwareRotation bool
+	// Dither is how images are reduced to 16-bit colors, see SetDither.
+	Dither Dither
+}
+
+// DefaultOpts matches the Pirate Audio wiring.
+// https://pinout.xyz/pinout/pirate_audio_line_out#
+var DefaultOpts = Options{
+	SPIPort:   "SPI0.1",
+	DC:        "GPIO9",
+	Backlight: "GPIO13",
+	Speed:     80 * physic.MegaHertz,
+	Mode:      spi.Mode0,
+	Width:     240,
+	Height:    240,
+}
+
+var (
+	sharedMu sync.Mutex
+	shared   *Display // returned by Init
+)
+
+// pinUsers counts the displays using each pin, so Close only releases the
+// pins no other display uses.
+var (
+	pinMu    sync.Mutex
+	pinUsers = map[gpio.PinIO]int{}
+)
+
+// Init returns the display with the default Pirate Audio options, shared by
+// every caller. It is initialized by the first successful call, failures are
+// not kept so Init can be retried. After the shared display is closed, the
+// next call initializes it again.
+func Init() (*Display, error) {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	if shared != nil {
+		return shared, nil
+	}
+	d, err := New(DefaultOpts)
+	if err != nil {
+		return nil, err
 	}
+	shared = d
+	return d, nil
+}
 
+// InitWithOptions initializes a display using the given wiring and bus
+// settings.
+//
+// Deprecated: use New.
+func InitWithOptions(opts Options) (*Display, error) {
+	return New(opts)
+}
+
+// New initializes a display using the given wiring and bus settings. Every
+// call returns an independent display owning its SPI port, so a failed
+// call can be retried. Pins can be shared with other displays.
+func New(opts Options) (*Display, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := host.Init(); err != nil {
+		return nil, err
+	}
 	if _, err := driverreg.Init(); err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
+	devOpts := st7789.DefaultOpts
+	devOpts.Width = opts.Width
+	devOpts.Height = opts.Height
+	devOpts.ColumnOffset = opts.ColumnOffset
+	devOpts.RowOffset = opts.RowOffset
+	devOpts.Speed = opts.Speed
+	devOpts.Mode = opts.Mode
+	devOpts.SoftwareRotation = opts.SoftwareRotation
+	devOpts.Dither = st7789.Dither(opts.Dither)
+	devOpts.Backlight = nil
+
+	d := &Display{}
+	dc, err := d.usePin(opts.DC)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Backlight != "" {
+		if devOpts.Backlight, err = d.usePin(opts.Backlight); err != nil {
+			d.release()
+			return nil, err
+		}
+	}
+	if opts.Reset != "" {
+		if devOpts.Reset, err = d.usePin(opts.Reset); err != nil {
+			d.release()
+			return nil, err
+		}
+	}
+
+	d.port, err = spireg.Open(opts.SPIPort)
+	if err != nil {
+		d.release()
+		return nil, err
+	}
+	d.dev, err = st7789.NewSPI(d.port, dc, &devOpts)
+	if err != nil {
+		d.release()
+		return nil, err
+	}
+	d.dirty = newDirtyTracker(d.dev.Bounds())
+	// The panel contents are unknown, so the first flush sends everything
+	d.dirty.mark(d.dev.Bounds())
+
+	return d, nil
 }
 
-func Init() (*Display, error) {
-	var err error
-	once.Do(func() {
-		display = &Display{}
-		display.port, err = spireg.Open("SPI0.1")
-		if err != nil {
-			return
+// withDefaults returns the options with the zero fields taken from
+// DefaultOpts.
+func (o Options) withDefaults() (Options, error) {
+	if o.SPIPort == "" {
+		o.SPIPort = DefaultOpts.SPIPort
+	}
+	if o.DC == "" {
+		o.DC = DefaultOpts.DC
+	}
+	if o.Speed == 0 {
+		o.Speed = DefaultOpts.Speed
+	}
+	if o.Width == 0 {
+		o.Width = DefaultOpts.Width
+	}
+	if o.Height == 0 {
+		o.Height = DefaultOpts.Height
+	}
+	if o.Width < 0 || o.Height < 0 {
+		return o, fmt.Errorf("%w: display size %dx%d", ErrOutOfBounds, o.Width, o.Height)
+	}
+	return o, nil
+}
+
+// FromDevice returns a display drawing on an already initialized device,
+// such as one backed by the st7789/emulator package. Close does not
+// release anything.
+func FromDevice(dev *st7789.Device) *Display {
+	d := &Display{dev: dev, dirty: newDirtyTracker(dev.Bounds())}
+	d.dirty.mark(dev.Bounds())
+	return d
+}
+
+// usePin looks a pin up and records that d uses it.
+func (d *Display) usePin(name string) (gpio.PinIO, error) {
+	p := gpioreg.ByName(name)
+	if p == nil {
+		return nil, fmt.Errorf("display: unknown pin %q", name)
+	}
+	pinMu.Lock()
+	defer pinMu.Unlock()
+	pinUsers[p]++
+	d.pins = append(d.pins, p)
+	return p, nil
+}
+
+// Close releases the SPI port and turns the pins used by the display back
+// into inputs, but for the pins another display still uses.
+func (d *Display) Close() error {
+	sharedMu.Lock()
+	if shared == d {
+		shared = nil
+	}
+	sharedMu.Unlock()
+	return d.release()
+}
+
+// release frees the port and the pins of d.
+func (d *Display) release() error {
+	var errs []error
+	if d.port != nil {
+		errs = append(errs, d.port.Close())
+		d.port = nil
+	}
+	pinMu.Lock()
+	defer pinMu.Unlock()
+	for _, p := range d.pins {
+		if pinUsers[p]--; pinUsers[p] > 0 {
+			continue
 		}
-		// USE GPIO9 to send data/commands
-		// https://pinout.xyz/pinout/pirate_audio_line_out#
-		display.dev, err = st7789.NewSPI(display.port.(spi.Port), gpioreg.ByName("GPIO9"), &st7789.DefaultOpts)
-	})
+		delete(pinUsers, p)
+		errs = append(errs, p.In(gpio.PullNoChange, gpio.NoEdge))
+	}
+	d.pins = nil
+	return errors.Join(errs...)
+}
+
+// DrawImage decodes an image and draws it covering the whole display.
+// Decoding errors wrap ErrDecode.
+func (d *Display) DrawImage(reader io.Reader) error {
+	img, _, err := image.Decode(reader)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDecode, err)
+	}
+	return d.DrawRAW(img)
+}
+
+// DrawRAW draws an image covering the whole display, sending only the
+// regions that changed.
+func (d *Display) DrawRAW(img image.Image) error {
+	d.dev.Blit(d.dev.Bounds(), img, img.Bounds().Min, d.dirty.mark)
+	return d.Flush()
+}
 
-	return display, err
+// DrawRegion draws the part of img within rect, leaving the rest of the
+// display untouched.
+func (d *Display) DrawRegion(rect image.Rectangle, img image.Image) error {
+	rect = rect.Intersect(d.dev.Bounds())
+	if rect.Empty() {
+		return fmt.Errorf("%w: region %v", ErrOutOfBounds, rect)
+	}
+	d.dev.Blit(rect, img, img.Bounds().Min.Add(rect.Min), d.dirty.mark)
+	return d.Flush()
 }
 
-func (d *Display) Close() {
-	d.port.Close()
+// Rotate changes the rotation of the display (clock-wise). Pending changes
+// are flushed first. Bounds follow the rotation, swapping width and height
+// for ROTATION_90 and ROTATION_270.
+func (d *Display) Rotate(rotation Rotation) error {
+	if err := d.Flush(); err != nil {
+		return err
+	}
+	if err := d.dev.SetRotation(st7789.Rotation(rotation)); err != nil {
+		return err
+	}
+	d.dirty = newDirtyTracker(d.dev.Bounds())
+	return nil
+}
+
+func (d *Display) FillScreen(c color.RGBA) error {
+	d.dev.Blit(d.dev.Bounds(), image.NewUniform(c), image.Point{}, d.dirty.mark)
+	return d.Flush()
+}
+
+func (d *Display) SetPixel(x int16, y int16, c color.RGBA) error {
+	if !(image.Point{int(x), int(y)}.In(d.dev.Bounds())) {
+		return fmt.Errorf("%w: pixel %d,%d", ErrOutOfBounds, x, y)
+	}
+	d.Set(int(x), int(y), c)
+	return d.Flush()
 }
 
-func (d *Display) DrawImage(reader io.Reader) {
-	d.dev.DrawImage(reader)
+// DefineScrollArea sets up a hardware scrolling band between fixed top and
+// bottom bands, see st7789.Device.DefineScrollArea. It returns ErrUnsupported
+// with ROTATION_90 and ROTATION_270.
+func (d *Display) DefineScrollArea(top, scroll, bottom int) error {
+	return d.dev.DefineScrollArea(top, scroll, bottom)
 }
 
-func (d *Display) DrawRAW(img image.Image) {
-	d.dev.DrawRAW(img)
+// ScrollTo scrolls the band set with DefineScrollArea to line.
+func (d *Display) ScrollTo(line int) error {
+	return d.dev.ScrollTo(line)
 }
 
-func (d *Display) Rotate(rotation Rotation) {
-	d.dev.SetRotation(st7789.Rotation(rotation))
+// ScrolledRow returns the framebuffer row currently shown on display row row.
+func (d *Display) ScrolledRow(row int) int {
+	return d.dev.ScrolledRow(row)
 }
 
-func (d *Display) FillScreen(c color.RGBA) {
-	d.dev.FillScreen(c)
+// ColorModel implements draw.Image.
+func (d *Display) ColorModel() color.Model {
+	return d.dev.ColorModel()
 }
 
-func (d *Display) SetPixel(x int16, y int16, c color.RGBA) {
-	d.dev.SetPixel(x, y, c)
+// Bounds implements draw.Image.
+func (d *Display) Bounds() image.Rectangle {
+	return d.dev.Bounds()
+}
+
+// At implements draw.Image.
+func (d *Display) At(x, y int) color.Color {
+	return d.dev.At(x, y)
+}
+
+// Set implements draw.Image. Changes are kept in memory until Flush is
+// called.
+func (d *Display) Set(x, y int, c color.Color) {
+	d.dev.Set(x, y, c)
+	d.dirty.mark(image.Rect(x, y, x+1, y+1))
+}
+
+// Flush sends the regions changed since the last flush to the display.
+// Regions that could not be sent stay dirty for the next call.
+func (d *Display) Flush() error {
+	rects := d.dirty.rects(d.merge)
+	if len(rects) == 0 {
+		return nil
+	}
+	d.dirty.reset()
+
+	sent := 0
+	var err error
+	for i, r := range rects {
+		if err = d.dev.FlushRegion(r); err != nil {
+			for _, r := range rects[i:] {
+				d.dirty.mark(r)
+			}
+			rects = rects[:i]
+			break
+		}
+		sent += windowCost(r)
+	}
+	if len(rects) == 0 {
+		return err
+	}
+	d.stats.Flushes++
+	d.stats.Windows += uint64(len(rects))
+	d.stats.BytesSent += uint64(sent)
+	if full := windowCost(d.dev.Bounds()); full > sent {
+		d.stats.BytesSaved += uint64(full - sent)
+	}
+	return err
+}
+
+// SetMergeStrategy changes how dirty regions are combined on Flush.
+func (d *Display) SetMergeStrategy(s MergeStrategy) {
+	d.merge = s
+}
+
+// Stats returns the counters of the data sent to the display.
+func (d *Display) Stats() Stats {
+	return d.stats
 }
 
 // PowerOff the display
-func (d *Display) PowerOff() {
-	d.dev.PowerOff()
+func (d *Display) PowerOff() error {
+	return d.dev.PowerOff()
+}
+
+// SetPowerMode switches the display to a low power mode or back to normal.
+// The partial modes return ErrUnsupported with ROTATION_90 and ROTATION_270.
+func (d *Display) SetPowerMode(mode PowerMode) error {
+	return d.dev.SetPowerMode(st7789.PowerMode(mode))
+}
+
+// SetPartialArea sets the rows, from start to end inclusive, shown in
+// POWER_PARTIAL and POWER_PARTIAL_IDLE modes. It returns ErrUnsupported
+// with ROTATION_90 and ROTATION_270.
+func (d *Display) SetPartialArea(start, end int) error {
+	return d.dev.SetPartialArea(start, end)
 }
 
 // PowerOn the display
-func (d *Display) PowerOn() {
-	d.dev.PowerOn()
+func (d *Display) PowerOn() error {
+	return d.dev.PowerOn()
 }
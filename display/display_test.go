@@ -0,0 +1,254 @@
+package display_test
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/rubiojr/go-pirateaudio/display"
+	"github.com/rubiojr/go-pirateaudio/display/displaytest"
+	"github.com/rubiojr/go-pirateaudio/st7789"
+	"github.com/rubiojr/go-pirateaudio/st7789/emulator"
+	"github.com/rubiojr/go-pirateaudio/textview"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// marker returns an image of size r with a gradient background and a
+// block in its top left corner, so any rotation or flip shows.
+func marker(r image.Rectangle) image.Image {
+	img := image.NewRGBA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetRGBA(x, y, color.RGBA{
+				R: uint8(x * 255 / r.Dx()),
+				G: uint8(y * 255 / r.Dy()),
+				B: 0x40,
+				A: 0xFF,
+			})
+		}
+	}
+	block := image.Rect(10, 10, 60, 30).Add(r.Min)
+	for y := block.Min.Y; y < block.Max.Y; y++ {
+		for x := block.Min.X; x < block.Max.X; x++ {
+			img.SetRGBA(x, y, color.RGBA{0xFF, 0xFF, 0xFF, 0xFF})
+		}
+	}
+	return img
+}
+
+func TestFillScreen(t *testing.T) {
+	dsp, panel := displaytest.New(t)
+	if err := dsp.FillScreen(color.RGBA{R: 0x20, G: 0x80, B: 0xE0, A: 0xFF}); err != nil {
+		t.Fatal(err)
+	}
+	displaytest.AssertGolden(t, "fill_screen", panel.Image())
+}
+
+func TestDrawRegion(t *testing.T) {
+	dsp, panel := displaytest.New(t)
+	if err := dsp.FillScreen(color.RGBA{A: 0xFF}); err != nil {
+		t.Fatal(err)
+	}
+	if err := dsp.DrawRegion(image.Rect(40, 60, 200, 140), marker(dsp.Bounds())); err != nil {
+		t.Fatal(err)
+	}
+	displaytest.AssertGolden(t, "draw_region", panel.Image())
+}
+
+func TestRotate(t *testing.T) {
+	for _, rotation := range []display.Rotation{
+		display.NO_ROTATION,
+		display.ROTATION_90,
+		display.ROTATION_180,
+		display.ROTATION_270,
+	} {
+		t.Run(fmt.Sprint(rotation), func(t *testing.T) {
+			dsp, panel := displaytest.New(t)
+			if err := dsp.Rotate(rotation); err != nil {
+				t.Fatal(err)
+			}
+			if err := dsp.DrawRAW(marker(dsp.Bounds())); err != nil {
+				t.Fatal(err)
+			}
+			displaytest.AssertGolden(t, fmt.Sprintf("rotate_%d", rotation), panel.Image())
+		})
+	}
+}
+
+func TestTextView(t *testing.T) {
+	// A font shipped with Go, so the output doesn't depend on the system
+	font := filepath.Join(t.TempDir(), "goregular.ttf")
+	if err := os.WriteFile(font, goregular.TTF, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dsp, panel := displaytest.New(t)
+	opts := textview.DefaultOpts
+	opts.FontPath = font
+	opts.FGColor = textview.YELLOW
+	opts.Display = dsp
+	tv, err := textview.Open(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tv.Draw("Pirate Audio: text wraps over several lines of the display"); err != nil {
+		t.Fatal(err)
+	}
+	displaytest.AssertGolden(t, "textview", panel.Image())
+}
+
+// lit reports whether any pixel of img is not black.
+func lit(img image.Image) bool {
+	r := img.Bounds()
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA); c.R|c.G|c.B != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestPowerOff(t *testing.T) {
+	dsp, panel := displaytest.New(t)
+	if err := dsp.FillScreen(color.RGBA{R: 0xFF, A: 0xFF}); err != nil {
+		t.Fatal(err)
+	}
+	if err := dsp.PowerOff(); err != nil {
+		t.Fatal(err)
+	}
+	if lit(panel.Image()) {
+		t.Error("display shows something with the backlight off")
+	}
+	if err := dsp.PowerOn(); err != nil {
+		t.Fatal(err)
+	}
+	displaytest.AssertGolden(t, "fill_red", panel.Image())
+}
+
+// windows returns the address windows the panel was written through, and
+// checks that each one got all its pixels.
+func windows(t *testing.T, panel *emulator.Panel) []image.Rectangle {
+	t.Helper()
+	var rs []image.Rectangle
+	var x0, x1, y0, y1 int
+	for _, c := range panel.Commands() {
+		switch c.Code {
+		case st7789.CASET:
+			x0, x1 = int(c.Params[0])<<8|int(c.Params[1]), int(c.Params[2])<<8|int(c.Params[3])
+		case st7789.RASET:
+			y0, y1 = int(c.Params[0])<<8|int(c.Params[1]), int(c.Params[2])<<8|int(c.Params[3])
+		case st7789.RAMWR:
+			r := image.Rect(x0, y0, x1+1, y1+1)
+			if c.Pixels != r.Dx()*r.Dy() {
+				t.Errorf("window %v got %d pixels, want %d", r, c.Pixels, r.Dx()*r.Dy())
+			}
+			rs = append(rs, r)
+		}
+	}
+	return rs
+}
+
+// cost is the number of bytes counted in Stats for sending rs.
+func cost(rs ...image.Rectangle) uint64 {
+	var n uint64
+	for _, r := range rs {
+		n += uint64(r.Dx()*r.Dy()*2 + 11)
+	}
+	return n
+}
+
+func TestSet(t *testing.T) {
+	dsp, panel := displaytest.New(t)
+	if err := dsp.FillScreen(color.RGBA{A: 0xFF}); err != nil {
+		t.Fatal(err)
+	}
+	panel.ClearCommands()
+
+	red, green := color.RGBA{R: 0xFF, A: 0xFF}, color.RGBA{G: 0xFF, A: 0xFF}
+	dsp.Set(10, 20, red)
+	dsp.Set(200, 100, green)
+	dsp.Set(-1, 300, red) // out of bounds, ignored
+	if c := dsp.At(10, 20); color.RGBAModel.Convert(c) != red {
+		t.Errorf("pixel 10,20 is %v before flushing, want red", c)
+	}
+	if rs := windows(t, panel); len(rs) != 0 {
+		t.Fatalf("sent windows %v before flushing", rs)
+	}
+	if err := dsp.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want := []image.Rectangle{image.Rect(10, 20, 11, 21), image.Rect(200, 100, 201, 101)}
+	if rs := windows(t, panel); !slices.Equal(rs, want) {
+		t.Errorf("sent windows %v, want %v", rs, want)
+	}
+	img := panel.Image()
+	if c := img.At(10, 20); c != red {
+		t.Errorf("panel pixel 10,20 is %v, want red", c)
+	}
+	if c := img.At(200, 100); c != green {
+		t.Errorf("panel pixel 200,100 is %v, want green", c)
+	}
+}
+
+func TestMergeStrategy(t *testing.T) {
+	// Two neighbour tiles, and a change far from them within another tile
+	a, b, c := image.Rect(0, 0, 16, 4), image.Rect(16, 0, 32, 4), image.Rect(194, 180, 206, 190)
+	full := cost(image.Rect(0, 0, 240, 240))
+	for _, tc := range []struct {
+		name     string
+		strategy display.MergeStrategy
+		want     []image.Rectangle
+	}{
+		{"cost", display.MERGE_COST, []image.Rectangle{a.Union(b), c}},
+		{"tiles", display.MERGE_TILES, []image.Rectangle{a, b, c}},
+		{"bounds", display.MERGE_BOUNDS, []image.Rectangle{a.Union(c)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dsp, panel := displaytest.New(t)
+			if err := dsp.FillScreen(color.RGBA{A: 0xFF}); err != nil {
+				t.Fatal(err)
+			}
+			if s := dsp.Stats(); s != (display.Stats{Flushes: 1, Windows: 1, BytesSent: full}) {
+				t.Fatalf("stats after the first flush %+v, want a single full window", s)
+			}
+			panel.ClearCommands()
+
+			dsp.SetMergeStrategy(tc.strategy)
+			white := image.NewUniform(color.RGBA{0xFF, 0xFF, 0xFF, 0xFF})
+			for _, r := range []image.Rectangle{a, b, c} {
+				draw.Draw(dsp, r, white, image.Point{}, draw.Src)
+			}
+			if err := dsp.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			if rs := windows(t, panel); !slices.Equal(rs, tc.want) {
+				t.Errorf("sent windows %v, want %v", rs, tc.want)
+			}
+			sent := cost(tc.want...)
+			want := display.Stats{
+				Flushes:    2,
+				Windows:    1 + uint64(len(tc.want)),
+				BytesSent:  full + sent,
+				BytesSaved: full - sent,
+			}
+			if s := dsp.Stats(); s != want {
+				t.Errorf("stats %+v, want %+v", s, want)
+			}
+
+			// Nothing changed, nothing sent
+			if err := dsp.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			if s := dsp.Stats(); s != want {
+				t.Errorf("stats after an empty flush %+v, want %+v", s, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+// Package displaytest renders display output on an emulated panel and
+// compares it with golden PNG files, for use in tests:
+//
+//	func TestClock(t *testing.T) {
+//		dsp, panel := displaytest.New(t)
+//		drawClock(dsp)
+//		displaytest.AssertGolden(t, "clock", panel.Image())
+//	}
+//
+// Run the tests with -update to write the golden files from the current
+// output.
+package displaytest
+
+import (
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rubiojr/go-pirateaudio/display"
+	"github.com/rubiojr/go-pirateaudio/st7789"
+	"github.com/rubiojr/go-pirateaudio/st7789/emulator"
+)
+
+var update = flag.Bool("update", false, "update golden files instead of comparing with them")
+
+// Options controls how images are compared with golden files.
+type Options struct {
+	// Dir holds the golden files.
+	Dir string
+	// Tolerance is the largest difference allowed in any colour channel
+	// before a pixel counts as different.
+	Tolerance uint8
+	// MaxDiffPixels is the number of different pixels allowed.
+	MaxDiffPixels int
+}
+
+// DefaultOpts requires an exact match with the files in testdata.
+var DefaultOpts = Options{
+	Dir: "testdata",
+}
+
+// New returns a 240x240 display backed by an emulated panel.
+func New(t testing.TB) (*display.Display, *emulator.Panel) {
+	t.Helper()
+	opts := st7789.DefaultOpts
+	return NewWithOptions(t, &opts)
+}
+
+// NewWithOptions returns a display backed by an emulated panel of the size
+// and offsets given in opts. The backlight is always the panel's.
+func NewWithOptions(t testing.TB, opts *st7789.Opts) (*display.Display, *emulator.Panel) {
+	t.Helper()
+	panel := emulator.NewWithOptions(opts)
+	devOpts := *opts
+	devOpts.Backlight = panel.Backlight()
+	dev, err := st7789.New(panel, panel.DC(), &devOpts)
+	if err != nil {
+		t.Fatalf("initializing emulated display: %v", err)
+	}
+	return display.FromDevice(dev), panel
+}
+
+// AssertGolden compares img with the golden file name.png using
+// DefaultOpts.
+func AssertGolden(t testing.TB, name string, img image.Image) {
+	t.Helper()
+	AssertGoldenWithOptions(t, name, img, DefaultOpts)
+}
+
+// AssertGoldenWithOptions compares img with the golden file name.png in
+// opts.Dir. On failure, name.actual.png and name.diff.png are written next
+// to it; the diff shows different pixels in red over a faded copy of the
+// golden image.
+func AssertGoldenWithOptions(t testing.TB, name string, img image.Image, opts Options) {
+	t.Helper()
+	path := filepath.Join(opts.Dir, name+".png")
+	if *update {
+		if err := writePNG(path, img); err != nil {
+			t.Fatalf("updating golden file: %v", err)
+		}
+		return
+	}
+
+	golden, err := readPNG(path)
+	if err != nil {
+		t.Fatalf("reading golden file (run with -update to create it): %v", err)
+	}
+	if golden.Bounds() != img.Bounds() {
+		t.Fatalf("%s: size %v, want %v", name, img.Bounds(), golden.Bounds())
+	}
+
+	diff, n := compare(golden, img, opts.Tolerance)
+	if n <= opts.MaxDiffPixels {
+		return
+	}
+	actualPath := filepath.Join(opts.Dir, name+".actual.png")
+	diffPath := filepath.Join(opts.Dir, name+".diff.png")
+	if err := writePNG(actualPath, img); err != nil {
+		t.Errorf("writing actual image: %v", err)
+	}
+	if err := writePNG(diffPath, diff); err != nil {
+		t.Errorf("writing diff image: %v", err)
+	}
+	t.Errorf("%s: %d pixels differ from the golden file (%d allowed), see %s", name, n, opts.MaxDiffPixels, diffPath)
+}
+
+// compare returns an image highlighting the pixels of b that differ from a
+// by more than tolerance in any channel, and how many there are.
+func compare(a, b image.Image, tolerance uint8) (*image.RGBA, int) {
+	r := a.Bounds()
+	diff := image.NewRGBA(r)
+	n := 0
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			ca := color.RGBAModel.Convert(a.At(x, y)).(color.RGBA)
+			cb := color.RGBAModel.Convert(b.At(x, y)).(color.RGBA)
+			if channelDiff(ca.R, cb.R) > tolerance || channelDiff(ca.G, cb.G) > tolerance ||
+				channelDiff(ca.B, cb.B) > tolerance || channelDiff(ca.A, cb.A) > tolerance {
+				diff.SetRGBA(x, y, color.RGBA{R: 0xFF, A: 0xFF})
+				n++
+				continue
+			}
+			gray := uint8((uint16(ca.R) + uint16(ca.G) + uint16(ca.B)) / 3 / 4)
+			diff.SetRGBA(x, y, color.RGBA{R: gray, G: gray, B: gray, A: 0xFF})
+		}
+	}
+	return diff, n
+}
+
+func channelDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+func readPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return png.Decode(f)
+}
+
+func writePNG(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
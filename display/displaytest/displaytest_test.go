@@ -0,0 +1,98 @@
+package displaytest
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// recorder is a testing.TB recording errors instead of failing the test.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertGoldenDiff(t *testing.T) {
+	golden := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for i := 3; i < len(golden.Pix); i += 4 {
+		golden.Pix[i] = 0xFF
+	}
+	opts := Options{Dir: t.TempDir()}
+	if err := writePNG(filepath.Join(opts.Dir, "img.png"), golden); err != nil {
+		t.Fatal(err)
+	}
+
+	img := image.NewRGBA(golden.Rect)
+	copy(img.Pix, golden.Pix)
+	img.SetRGBA(3, 4, color.RGBA{R: 10, A: 0xFF})
+
+	for _, tc := range []struct {
+		name      string
+		tolerance uint8
+		maxDiff   int
+		fail      bool
+	}{
+		{"exact", 0, 0, true},
+		{"tolerance", 10, 0, false},
+		{"max diff pixels", 0, 1, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Remove(filepath.Join(opts.Dir, "img.diff.png"))
+			rec := &recorder{TB: t}
+			opts := opts
+			opts.Tolerance = tc.tolerance
+			opts.MaxDiffPixels = tc.maxDiff
+			AssertGoldenWithOptions(rec, "img", img, opts)
+
+			if failed := len(rec.errors) > 0; failed != tc.fail {
+				t.Fatalf("failed: %v, want %v (errors: %q)", failed, tc.fail, rec.errors)
+			}
+			if !tc.fail {
+				return
+			}
+			actual, err := readPNG(filepath.Join(opts.Dir, "img.actual.png"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := countDiff(actual, img); n != 0 {
+				t.Errorf("actual image differs from the drawn one in %d pixels", n)
+			}
+			diff, err := readPNG(filepath.Join(opts.Dir, "img.diff.png"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			red := color.RGBA{R: 0xFF, A: 0xFF}
+			if c := color.RGBAModel.Convert(diff.At(3, 4)); c != red {
+				t.Errorf("diff pixel 3,4 is %v, want red", c)
+			}
+			if c := color.RGBAModel.Convert(diff.At(0, 0)); c == red {
+				t.Errorf("diff pixel 0,0 is red, want the faded golden image")
+			}
+		})
+	}
+}
+
+func countDiff(a, b image.Image) int {
+	_, n := compare(a, b, 0)
+	return n
+}
+
+func TestAssertGoldenUpdate(t *testing.T) {
+	defer func(u bool) { *update = u }(*update)
+	*update = true
+
+	opts := Options{Dir: filepath.Join(t.TempDir(), "golden")}
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	img.SetRGBA(1, 2, color.RGBA{G: 0xFF, A: 0xFF})
+	AssertGoldenWithOptions(t, "img", img, opts)
+
+	*update = false
+	AssertGoldenWithOptions(t, "img", img, opts)
+}
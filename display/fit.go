@@ -0,0 +1,151 @@
+package display
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+
+	"github.com/rubiojr/go-pirateaudio/st7789"
+	"golang.org/x/image/draw"
+)
+
+type ScaleMode uint8
+
+const (
+	SCALE_FIT     ScaleMode = 0 // scale to fit, with bars of Background on the sides
+	SCALE_FILL    ScaleMode = 1 // scale to cover the display, cropping the center
+	SCALE_STRETCH ScaleMode = 2 // scale to the display size, ignoring the aspect ratio
+	SCALE_CENTER  ScaleMode = 3 // keep the size, centered and cropped if larger
+	SCALE_TILE    ScaleMode = 4 // keep the size, repeated from the top left corner
+)
+
+type Filter uint8
+
+const (
+	FILTER_NEAREST     Filter = 0 // fastest, blocky when enlarging
+	FILTER_BILINEAR    Filter = 1
+	FILTER_CATMULL_ROM Filter = 2 // sharpest, slowest
+)
+
+type Dither uint8
+
+const (
+	DITHER_NONE            Dither = 0 // truncate the low bits, fastest
+	DITHER_BAYER_4         Dither = 1 // ordered, with a 4x4 Bayer matrix
+	DITHER_BAYER_8         Dither = 2 // ordered, with an 8x8 Bayer matrix
+	DITHER_FLOYD_STEINBERG Dither = 3 // error diffusion, smoothest, slowest
+)
+
+// DrawImageOpts defines how images of any size are drawn on the display.
+type DrawImageOpts struct {
+	Scale  ScaleMode
+	Filter Filter
+	// Background fills the display where the image doesn't cover it, and
+	// shows through transparent pixels. Black if nil.
+	Background color.Color
+	// Dither reduces banding when the image is converted to 16-bit colors.
+	// The display's, set with SetDither, if nil.
+	Dither *Dither
+}
+
+// DefaultDrawImageOpts fits images to the display, with black bars.
+var DefaultDrawImageOpts = DrawImageOpts{
+	Scale:      SCALE_FIT,
+	Filter:     FILTER_BILINEAR,
+	Background: color.Black,
+}
+
+// DrawImageWithOptions decodes an image and draws it on the whole display,
+// scaled as set in opts. Decoding errors wrap ErrDecode.
+func (d *Display) DrawImageWithOptions(reader io.Reader, opts DrawImageOpts) error {
+	img, _, err := image.Decode(reader)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDecode, err)
+	}
+	return d.DrawRAWWithOptions(img, opts)
+}
+
+// DrawRAWWithOptions draws an image of any size on the whole display,
+// scaled as set in opts.
+func (d *Display) DrawRAWWithOptions(img image.Image, opts DrawImageOpts) error {
+	if opts.Dither != nil {
+		def := d.dev.Dither()
+		d.dev.SetDither(st7789.Dither(*opts.Dither))
+		defer d.dev.SetDither(def)
+	}
+	return d.DrawRAW(fit(img, d.Bounds(), opts))
+}
+
+// SetDither sets how images are reduced to 16-bit colors when drawn without
+// a Dither in DrawImageOpts.
+func (d *Display) SetDither(dither Dither) {
+	d.dev.SetDither(st7789.Dither(dither))
+}
+
+// Dither returns the dithering set with SetDither or Options.Dither.
+func (d *Display) Dither() Dither {
+	return Dither(d.dev.Dither())
+}
+
+// fit returns img laid out on an image of size bounds as set in opts.
+func fit(img image.Image, bounds image.Rectangle, opts DrawImageOpts) image.Image {
+	bg := opts.Background
+	if bg == nil {
+		bg = color.Black
+	}
+	dst := image.NewRGBA(bounds)
+	draw.Draw(dst, bounds, image.NewUniform(bg), image.Point{}, draw.Src)
+
+	src := img.Bounds()
+	if src.Empty() {
+		return dst
+	}
+	if opts.Scale == SCALE_TILE {
+		for y := bounds.Min.Y; y < bounds.Max.Y; y += src.Dy() {
+			for x := bounds.Min.X; x < bounds.Max.X; x += src.Dx() {
+				r := src.Sub(src.Min).Add(image.Pt(x, y))
+				draw.Draw(dst, r, img, src.Min, draw.Over)
+			}
+		}
+		return dst
+	}
+
+	r := scaledRect(src.Size(), bounds, opts.Scale)
+	if r.Size() == src.Size() {
+		draw.Draw(dst, r, img, src.Min, draw.Over)
+		return dst
+	}
+	interpolator(opts.Filter).Scale(dst, r, img, src, draw.Over, nil)
+	return dst
+}
+
+// scaledRect returns where an image of size size goes within bounds. It
+// can extend past bounds, which crops the image.
+func scaledRect(size image.Point, bounds image.Rectangle, mode ScaleMode) image.Rectangle {
+	w, h := bounds.Dx(), bounds.Dy()
+	switch mode {
+	case SCALE_STRETCH:
+		return bounds
+	case SCALE_FIT, SCALE_FILL:
+		sx := float64(w) / float64(size.X)
+		sy := float64(h) / float64(size.Y)
+		s := min(sx, sy)
+		if mode == SCALE_FILL {
+			s = max(sx, sy)
+		}
+		size = image.Pt(max(int(float64(size.X)*s+0.5), 1), max(int(float64(size.Y)*s+0.5), 1))
+	}
+	p := bounds.Min.Add(image.Pt((w-size.X)/2, (h-size.Y)/2))
+	return image.Rectangle{Min: p, Max: p.Add(size)}
+}
+
+func interpolator(f Filter) draw.Interpolator {
+	switch f {
+	case FILTER_BILINEAR:
+		return draw.BiLinear
+	case FILTER_CATMULL_ROM:
+		return draw.CatmullRom
+	}
+	return draw.NearestNeighbor
+}
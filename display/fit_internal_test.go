@@ -0,0 +1,162 @@
+package display
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestScaledRect(t *testing.T) {
+	square := image.Rect(0, 0, 240, 240)
+	for _, tc := range []struct {
+		name   string
+		size   image.Point
+		bounds image.Rectangle
+		mode   ScaleMode
+		want   image.Rectangle
+	}{
+		{"fit wide", image.Pt(480, 240), square, SCALE_FIT, image.Rect(0, 60, 240, 180)},
+		{"fit tall", image.Pt(100, 200), square, SCALE_FIT, image.Rect(60, 0, 180, 240)},
+		{"fit small", image.Pt(60, 30), square, SCALE_FIT, image.Rect(0, 60, 240, 180)},
+		{"fit wide display", image.Pt(100, 100), image.Rect(0, 0, 320, 240), SCALE_FIT, image.Rect(40, 0, 280, 240)},
+		{"fit thin", image.Pt(1, 1000), square, SCALE_FIT, image.Rect(119, 0, 120, 240)},
+		{"fill wide", image.Pt(480, 240), square, SCALE_FILL, image.Rect(-120, 0, 360, 240)},
+		{"fill tall", image.Pt(100, 200), square, SCALE_FILL, image.Rect(0, -120, 240, 360)},
+		{"stretch", image.Pt(480, 10), square, SCALE_STRETCH, square},
+		{"center larger", image.Pt(300, 100), square, SCALE_CENTER, image.Rect(-30, 70, 270, 170)},
+		{"center smaller", image.Pt(100, 50), square, SCALE_CENTER, image.Rect(70, 95, 170, 145)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := scaledRect(tc.size, tc.bounds, tc.mode); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// coords returns an image of bounds r where each pixel encodes its
+// position, which must be below 256.
+func coords(r image.Rectangle) *image.RGBA {
+	img := image.NewRGBA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetRGBA(x, y, color.RGBA{uint8(x), uint8(y), 0x80, 0xFF})
+		}
+	}
+	return img
+}
+
+// bands returns a w x h image with vertical bands of the colors cs.
+func bands(w, h int, cs ...color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetRGBA(x, y, cs[x*len(cs)/w])
+		}
+	}
+	return img
+}
+
+func TestFit(t *testing.T) {
+	var (
+		red   = color.RGBA{R: 0xFF, A: 0xFF}
+		green = color.RGBA{G: 0xFF, A: 0xFF}
+		blue  = color.RGBA{B: 0xFF, A: 0xFF}
+		black = color.RGBA{A: 0xFF}
+	)
+	bounds := image.Rect(0, 0, 240, 240)
+	for _, tc := range []struct {
+		name string
+		img  image.Image
+		opts DrawImageOpts
+		want func(x, y int) color.RGBA
+	}{
+		{
+			"fit letterbox",
+			bands(480, 240, red),
+			DrawImageOpts{Scale: SCALE_FIT, Background: blue},
+			func(x, y int) color.RGBA {
+				if y < 60 || y >= 180 {
+					return blue
+				}
+				return red
+			},
+		},
+		{
+			"fill crops the center",
+			bands(480, 240, green, red, green),
+			DrawImageOpts{Scale: SCALE_FILL},
+			func(x, y int) color.RGBA {
+				if x < 40 || x >= 200 {
+					return green
+				}
+				return red
+			},
+		},
+		{
+			"stretch",
+			bands(2, 1, red, blue),
+			DrawImageOpts{Scale: SCALE_STRETCH, Filter: FILTER_NEAREST},
+			func(x, y int) color.RGBA {
+				if x < 120 {
+					return red
+				}
+				return blue
+			},
+		},
+		{
+			"center crops a larger image",
+			coords(image.Rect(5, 10, 255, 255)),
+			DrawImageOpts{Scale: SCALE_CENTER},
+			func(x, y int) color.RGBA {
+				// Offset by the bounds and half the extra size
+				return color.RGBA{uint8(x + 5 + 5), uint8(y + 10 + 2), 0x80, 0xFF}
+			},
+		},
+		{
+			"center leaves the background around",
+			bands(100, 100, red),
+			DrawImageOpts{Scale: SCALE_CENTER, Background: green},
+			func(x, y int) color.RGBA {
+				if x < 70 || x >= 170 || y < 70 || y >= 170 {
+					return green
+				}
+				return red
+			},
+		},
+		{
+			"tile from the image origin",
+			coords(image.Rect(5, 7, 105, 57)),
+			DrawImageOpts{Scale: SCALE_TILE},
+			func(x, y int) color.RGBA {
+				return color.RGBA{uint8(5 + x%100), uint8(7 + y%50), 0x80, 0xFF}
+			},
+		},
+		{
+			"empty image",
+			image.NewRGBA(image.Rect(3, 3, 3, 10)),
+			DrawImageOpts{Scale: SCALE_FIT, Background: blue},
+			func(x, y int) color.RGBA { return blue },
+		},
+		{
+			"black without background",
+			image.NewRGBA(image.Rectangle{}),
+			DrawImageOpts{},
+			func(x, y int) color.RGBA { return black },
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := fit(tc.img, bounds, tc.opts)
+			if got.Bounds() != bounds {
+				t.Fatalf("bounds %v, want %v", got.Bounds(), bounds)
+			}
+			for y := 0; y < bounds.Dy(); y++ {
+				for x := 0; x < bounds.Dx(); x++ {
+					if c, want := color.RGBAModel.Convert(got.At(x, y)), tc.want(x, y); c != want {
+						t.Fatalf("pixel %d,%d is %v, want %v", x, y, c, want)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package display_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/rubiojr/go-pirateaudio/display"
+	"github.com/rubiojr/go-pirateaudio/display/displaytest"
+	"github.com/rubiojr/go-pirateaudio/st7789"
+)
+
+func TestDitherValues(t *testing.T) {
+	for _, d := range []struct {
+		display display.Dither
+		st7789  st7789.Dither
+	}{
+		{display.DITHER_NONE, st7789.DITHER_NONE},
+		{display.DITHER_BAYER_4, st7789.DITHER_BAYER_4},
+		{display.DITHER_BAYER_8, st7789.DITHER_BAYER_8},
+		{display.DITHER_FLOYD_STEINBERG, st7789.DITHER_FLOYD_STEINBERG},
+	} {
+		if st7789.Dither(d.display) != d.st7789 {
+			t.Errorf("display dither %d converts to st7789 %d, want %d", d.display, st7789.Dither(d.display), d.st7789)
+		}
+	}
+}
+
+// gradient returns a horizontal gray gradient, which bands without
+// dithering.
+func gradient(r image.Rectangle) image.Image {
+	img := image.NewRGBA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			v := uint8(x * 64 / r.Dx())
+			img.SetRGBA(x, y, color.RGBA{v, v, v, 0xFF})
+		}
+	}
+	return img
+}
+
+func TestDrawRAWWithOptionsDither(t *testing.T) {
+	fs := display.DITHER_FLOYD_STEINBERG
+
+	ref, refPanel := displaytest.New(t)
+	ref.SetDither(fs)
+	if err := ref.DrawRAWWithOptions(gradient(ref.Bounds()), display.DefaultDrawImageOpts); err != nil {
+		t.Fatal(err)
+	}
+
+	dsp, panel := displaytest.New(t)
+	dsp.SetDither(display.DITHER_BAYER_4)
+	opts := display.DefaultDrawImageOpts
+	opts.Dither = &fs
+	if err := dsp.DrawRAWWithOptions(gradient(dsp.Bounds()), opts); err != nil {
+		t.Fatal(err)
+	}
+	if dsp.Dither() != display.DITHER_BAYER_4 {
+		t.Errorf("dither after drawing is %d, want the display's DITHER_BAYER_4", dsp.Dither())
+	}
+	got, want := panel.Image(), refPanel.Image()
+	for y := 0; y < got.Bounds().Dy(); y++ {
+		for x := 0; x < got.Bounds().Dx(); x++ {
+			if got.At(x, y) != want.At(x, y) {
+				t.Fatalf("pixel %d,%d is %v, want %v as with the display set to Floyd-Steinberg", x, y, got.At(x, y), want.At(x, y))
+			}
+		}
+	}
+}
+
+// columnMeans returns the mean of each channel in every column of img.
+func columnMeans(img image.Image) [][3]float64 {
+	r := img.Bounds()
+	means := make([][3]float64, r.Dx())
+	for x := r.Min.X; x < r.Max.X; x++ {
+		m := &means[x-r.Min.X]
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+			m[0] += float64(c.R)
+			m[1] += float64(c.G)
+			m[2] += float64(c.B)
+		}
+		for i := range m {
+			m[i] /= float64(r.Dy())
+		}
+	}
+	return means
+}
+
+// longestBand returns the widest run of neighbour columns with the same
+// means.
+func longestBand(means [][3]float64) int {
+	longest, n := 0, 0
+	for i := range means {
+		if i > 0 && means[i] == means[i-1] {
+			n++
+		} else {
+			n = 1
+		}
+		longest = max(longest, n)
+	}
+	return longest
+}
+
+func TestDitherGradient(t *testing.T) {
+	// The mean error of a column, made of the rounding of the thresholds
+	// and of the expansion of 565 colors back to 8 bits
+	const tolerance = 4
+
+	dsp, panel := displaytest.New(t)
+	src := gradient(dsp.Bounds())
+	if err := dsp.DrawRAW(src); err != nil {
+		t.Fatal(err)
+	}
+	banded := longestBand(columnMeans(panel.Image()))
+
+	for _, tc := range []struct {
+		name   string
+		dither display.Dither
+	}{
+		{"Bayer4", display.DITHER_BAYER_4},
+		{"Bayer8", display.DITHER_BAYER_8},
+		{"FloydSteinberg", display.DITHER_FLOYD_STEINBERG},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dsp, panel := displaytest.New(t)
+			dsp.SetDither(tc.dither)
+			if err := dsp.DrawRAW(src); err != nil {
+				t.Fatal(err)
+			}
+			means := columnMeans(panel.Image())
+			for x, m := range means {
+				want := float64(color.RGBAModel.Convert(src.At(x, 0)).(color.RGBA).R)
+				for i, v := range m {
+					if v < want-tolerance || v > want+tolerance {
+						t.Fatalf("column %d channel %d mean is %.2f, want %.0f±%d", x, i, v, want, tolerance)
+					}
+				}
+			}
+			if n := longestBand(means); n >= banded {
+				t.Errorf("widest band is %d columns, want less than the %d without dithering", n, banded)
+			}
+		})
+	}
+}
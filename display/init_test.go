@@ -0,0 +1,142 @@
+package display_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/rubiojr/go-pirateaudio/display"
+	"periph.io/x/conn/v3"
+	"periph.io/x/conn/v3/gpio"
+	"periph.io/x/conn/v3/gpio/gpioreg"
+	"periph.io/x/conn/v3/gpio/gpiotest"
+	"periph.io/x/conn/v3/physic"
+	"periph.io/x/conn/v3/spi"
+	"periph.io/x/conn/v3/spi/spireg"
+)
+
+// port is an SPI port dropping everything sent to it.
+type port struct{}
+
+func (port) String() string                    { return "TEST_SPI" }
+func (port) Close() error                      { return nil }
+func (port) LimitSpeed(physic.Frequency) error { return nil }
+func (port) Connect(physic.Frequency, spi.Mode, int) (spi.Conn, error) {
+	return port{}, nil
+}
+func (port) Duplex() conn.Duplex          { return conn.Half }
+func (port) Tx(w, r []byte) error         { return nil }
+func (port) TxPackets([]spi.Packet) error { return nil }
+
+// pin is a registered test pin counting how many times it was released.
+type pin struct {
+	gpiotest.Pin
+	mu       sync.Mutex
+	released int
+}
+
+func (p *pin) In(pull gpio.Pull, edge gpio.Edge) error {
+	p.mu.Lock()
+	p.released++
+	p.mu.Unlock()
+	return p.Pin.In(pull, edge)
+}
+
+func (p *pin) releases() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.released
+}
+
+// Registered once, periph's registries can't unregister
+var (
+	registerOnce sync.Once
+	testDC       = &pin{Pin: gpiotest.Pin{N: "TEST_DC"}}
+	testBL       = &pin{Pin: gpiotest.Pin{N: "TEST_BL"}}
+)
+
+// testOpts returns options for the test port and pins, registering them.
+func testOpts(t *testing.T) display.Options {
+	t.Helper()
+	registerOnce.Do(func() {
+		for _, p := range []*pin{testDC, testBL} {
+			if err := gpioreg.Register(p); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := spireg.Register("TEST_SPI", nil, -1, func() (spi.PortCloser, error) { return port{}, nil }); err != nil {
+			t.Fatal(err)
+		}
+	})
+	return display.Options{SPIPort: "TEST_SPI", DC: "TEST_DC", Backlight: "TEST_BL", Width: 240, Height: 240}
+}
+
+func TestInitShared(t *testing.T) {
+	opts := testOpts(t)
+	defer func(o display.Options) { display.DefaultOpts = o }(display.DefaultOpts)
+
+	// Failures are not kept
+	display.DefaultOpts = opts
+	display.DefaultOpts.SPIPort = "MISSING_SPI"
+	if _, err := display.Init(); err == nil {
+		t.Fatal("Init succeeded without an SPI port")
+	}
+	display.DefaultOpts = opts
+	d1, err := display.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d2, err := display.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d1 != d2 {
+		t.Error("Init returned two different displays")
+	}
+	d3, err := display.New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d3.Close()
+	if d3 == d1 {
+		t.Error("New returned the shared display")
+	}
+
+	// Closing the shared display makes Init start again
+	if err := d1.Close(); err != nil {
+		t.Fatal(err)
+	}
+	d4, err := display.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d4.Close()
+	if d4 == d1 {
+		t.Error("Init returned the closed display")
+	}
+}
+
+func TestCloseSharedPins(t *testing.T) {
+	opts := testOpts(t)
+	dc, bl := testDC.releases(), testBL.releases()
+
+	d1, err := display.New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d2, err := display.New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d1.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if testDC.releases() != dc || testBL.releases() != bl {
+		t.Fatal("pins released while another display uses them")
+	}
+	if err := d2.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if testDC.releases() != dc+1 || testBL.releases() != bl+1 {
+		t.Errorf("pins released %d and %d times after closing the last display, want once", testDC.releases()-dc, testBL.releases()-bl)
+	}
+}
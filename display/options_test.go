@@ -0,0 +1,39 @@
+package display
+
+import (
+	"errors"
+	"testing"
+
+	"periph.io/x/conn/v3/physic"
+)
+
+func TestOptionsWithDefaults(t *testing.T) {
+	got, err := Options{Height: 320, Backlight: "GPIO12"}.withDefaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Options{
+		SPIPort:   DefaultOpts.SPIPort,
+		DC:        DefaultOpts.DC,
+		Backlight: "GPIO12",
+		Speed:     DefaultOpts.Speed,
+		Width:     DefaultOpts.Width,
+		Height:    320,
+	}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// Empty pins are not connected
+	got, err = Options{Speed: 40 * physic.MegaHertz}.withDefaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Backlight != "" || got.Reset != "" || got.Speed != 40*physic.MegaHertz {
+		t.Errorf("got %+v, want no backlight nor reset at 40MHz", got)
+	}
+
+	if _, err := (Options{Width: -1}).withDefaults(); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("negative width returned %v, want ErrOutOfBounds", err)
+	}
+}
@@ -2,27 +2,44 @@ package main
 
 import (
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/rubiojr/go-pirateaudio/buttons"
 )
 
 func main() {
-	buttons.OnButtonAPressed(func() {
+	a, err := buttons.OnButtonAPressed(func() {
 		fmt.Println("Yo Dawg, A pressed")
 	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer a.Stop()
 
-	buttons.OnButtonXPressed(func() {
+	x, err := buttons.OnButtonXPressed(func() {
 		fmt.Println("Yo Dawg, X pressed")
 	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer x.Stop()
 
-	buttons.OnButtonYPressed(func() {
+	y, err := buttons.OnButtonYPressed(func() {
 		fmt.Println("Yo Dawg, Y pressed")
 	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer y.Stop()
 
-	buttons.OnButtonBPressed(func() {
+	b, err := buttons.OnButtonBPressed(func() {
 		fmt.Println("Yo Dawg, B pressed")
 	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer b.Stop()
 
 	for {
 		time.Sleep(1)
@@ -23,7 +23,9 @@ func main() {
 	defer dsp.Close()
 
 	// Set the screen color to white
-	dsp.FillScreen(color.RGBA{R: 0, G: 0, B: 0, A: 0})
+	if err := dsp.FillScreen(color.RGBA{R: 0, G: 0, B: 0, A: 0}); err != nil {
+		log.Fatal(err)
+	}
 
 	img, err := os.Open(os.Args[1])
 	if err != nil {
@@ -32,6 +34,10 @@ func main() {
 	defer img.Close()
 
 	// Rotate before pushing pixels, so the image appears rotated
-	dsp.Rotate(display.ROTATION_180)
-	dsp.DrawImage(img)
+	if err := dsp.Rotate(display.ROTATION_180); err != nil {
+		log.Fatal(err)
+	}
+	if err := dsp.DrawImage(img); err != nil {
+		log.Fatal(err)
+	}
 }
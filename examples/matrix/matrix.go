@@ -1,6 +1,7 @@
 package main
 
 import (
+	"log"
 	"time"
 
 	"github.com/rubiojr/go-pirateaudio/textview"
@@ -9,7 +10,10 @@ import (
 func main() {
 	opts := textview.DefaultOpts
 	opts.FGColor = textview.GREEN
-	tv := textview.NewWithOptions(opts)
+	tv, err := textview.Open(opts)
+	if err != nil {
+		log.Fatal(err)
+	}
 	tv.Draw("")
 	time.Sleep(3 * time.Second)
 	tv.DrawChars("Wake up, Neo...")
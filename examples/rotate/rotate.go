@@ -24,25 +24,39 @@ func main() {
 	}
 	defer dsp.Close()
 
-	dsp.FillScreen(color.RGBA{R: 0, G: 0, B: 0, A: 0})
+	if err := dsp.FillScreen(color.RGBA{R: 0, G: 0, B: 0, A: 0}); err != nil {
+		log.Fatal(err)
+	}
 
 	var rotation display.Rotation
 	rotation = 0
-	buttons.OnButtonAPressed(func() {
-		dsp.FillScreen(color.RGBA{R: 0, G: 0, B: 0, A: 0})
+	h, err := buttons.OnButtonAPressed(func() {
+		if err := dsp.FillScreen(color.RGBA{R: 0, G: 0, B: 0, A: 0}); err != nil {
+			log.Print(err)
+			return
+		}
 		// Rotate before pushing pixels, so the image appears rotated
-		dsp.Rotate(rotation)
+		if err := dsp.Rotate(rotation); err != nil {
+			log.Print(err)
+			return
+		}
 		img, err := os.Open(os.Args[1])
 		if err != nil {
 			log.Fatal(err)
 		}
 		defer img.Close()
-		dsp.DrawImage(img)
+		if err := dsp.DrawImage(img); err != nil {
+			log.Print(err)
+		}
 		rotation++
 		if rotation > 3 {
 			rotation = 0
 		}
 	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer h.Stop()
 
 	for {
 		time.Sleep(1)
@@ -0,0 +1,36 @@
+// Print button events, pressing the buttons from the keyboard
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/rubiojr/go-pirateaudio/buttons"
+	"github.com/rubiojr/go-pirateaudio/buttons/sim"
+)
+
+func main() {
+	s := sim.New()
+	opts := buttons.DefaultOpts
+	opts.Board = s.Board()
+	if err := buttons.Configure(opts); err != nil {
+		log.Fatal(err)
+	}
+
+	events, err := buttons.Events(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+	go func() {
+		for ev := range events {
+			fmt.Println(ev)
+		}
+	}()
+
+	fmt.Println("a/b/x/y click a button, A/B/X/Y press or release it, then Enter")
+	if err := s.Keyboard(os.Stdin); err != nil {
+		log.Fatal(err)
+	}
+}
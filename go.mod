@@ -5,13 +5,14 @@ go 1.22.0
 require (
 	github.com/fogleman/gg v1.3.0
 	github.com/muesli/reflow v0.3.0
+	golang.org/x/image v0.15.0
 	periph.io/x/conn/v3 v3.7.0
 	periph.io/x/host/v3 v3.8.2
 )
 
 require (
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
+	github.com/jonboulle/clockwork v0.3.0 // indirect
 	github.com/mattn/go-runewidth v0.0.12 // indirect
 	github.com/rivo/uniseg v0.2.0 // indirect
-	golang.org/x/image v0.15.0 // indirect
 )
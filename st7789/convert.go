@@ -0,0 +1,152 @@
+package st7789
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// rowConverter writes n pixels of an image, from sx, sy onwards, to dst as
+// big-endian RGB565.
+type rowConverter func(dst []byte, sx, sy, n int)
+
+// pack565 packs 16-bit color channels, as returned by color.Color.RGBA.
+func pack565(r, g, b uint32) uint16 {
+	return uint16(r&0xF800 | (g&0xFC00)>>5 | b>>11)
+}
+
+// pack565x8 packs 8-bit color channels.
+func pack565x8(r, g, b uint8) uint16 {
+	return uint16(r&0xF8)<<8 | uint16(g&0xFC)<<3 | uint16(b>>3)
+}
+
+// ycbcrTo565 is color.YCbCrToRGB followed by pack565x8, inlined for speed.
+func ycbcrTo565(y, cb, cr uint8) uint16 {
+	yy := int32(y) * 0x10101
+	cb1 := int32(cb) - 128
+	cr1 := int32(cr) - 128
+	return uint16(clamp8(yy+91881*cr1)&0xF8)<<8 |
+		uint16(clamp8(yy-22554*cb1-46802*cr1)&0xFC)<<3 |
+		uint16(clamp8(yy+116130*cb1)>>3)
+}
+
+// clamp8 returns the 8-bit channel of a 16.16 fixed point value, clamped.
+func clamp8(v int32) uint8 {
+	if uint32(v)&0xFF000000 == 0 {
+		return uint8(v >> 16)
+	}
+	return uint8(^(v >> 31))
+}
+
+func put565(dst []byte, c uint16) {
+	dst[0] = uint8(c >> 8)
+	dst[1] = uint8(c)
+}
+
+// converter returns a rowConverter for img, using a fast path for the
+// common image types. Other types are drawn into d.scratch first, covering
+// r at sp, so only the pixels in r can be converted. Colors are composed
+// over black, like draw.Src into an opaque image. RGB565Image pixels are
+// copied as is, everything else is dithered as set with SetDither.
+func (d *Device) converter(img image.Image, r image.Rectangle, sp image.Point) rowConverter {
+	if _, ok := img.(*RGB565Image); !ok && d.dither != DITHER_NONE {
+		return d.ditherer(img, r, sp)
+	}
+	switch src := img.(type) {
+	case *RGB565Image:
+		return func(dst []byte, sx, sy, n int) {
+			i := src.PixOffset(sx, sy)
+			copy(dst[:n*2], src.Pix[i:i+n*2])
+		}
+	case *image.Uniform:
+		r, g, b, _ := src.C.RGBA()
+		c := pack565(r, g, b)
+		return func(dst []byte, sx, sy, n int) {
+			for i := 0; i < n; i++ {
+				put565(dst[i*2:], c)
+			}
+		}
+	case *image.RGBA:
+		return func(dst []byte, sx, sy, n int) {
+			pix := src.Pix[src.PixOffset(sx, sy):]
+			for i := 0; i < n; i++ {
+				p := pix[i*4 : i*4+3 : i*4+3]
+				put565(dst[i*2:], pack565x8(p[0], p[1], p[2]))
+			}
+		}
+	case *image.NRGBA:
+		return func(dst []byte, sx, sy, n int) {
+			pix := src.Pix[src.PixOffset(sx, sy):]
+			for i := 0; i < n; i++ {
+				p := pix[i*4 : i*4+4 : i*4+4]
+				if p[3] == 0xFF {
+					put565(dst[i*2:], pack565x8(p[0], p[1], p[2]))
+					continue
+				}
+				r, g, b, _ := color.NRGBA{p[0], p[1], p[2], p[3]}.RGBA()
+				put565(dst[i*2:], pack565(r, g, b))
+			}
+		}
+	case *image.Gray:
+		return func(dst []byte, sx, sy, n int) {
+			pix := src.Pix[src.PixOffset(sx, sy):]
+			for i := 0; i < n; i++ {
+				put565(dst[i*2:], pack565x8(pix[i], pix[i], pix[i]))
+			}
+		}
+	case *image.YCbCr:
+		if src.Rect.Min.X < 0 {
+			// Shifts don't round like COffset for negative columns
+			break
+		}
+		// Horizontal chroma subsampling, as a shift
+		var h uint
+		switch src.SubsampleRatio {
+		case image.YCbCrSubsampleRatio422, image.YCbCrSubsampleRatio420:
+			h = 1
+		case image.YCbCrSubsampleRatio411, image.YCbCrSubsampleRatio410:
+			h = 2
+		}
+		return func(dst []byte, sx, sy, n int) {
+			yi := src.YOffset(sx, sy)
+			// Offset of the chroma sample of column 0, which may not exist
+			c0 := src.COffset(sx, sy) - sx>>h
+			for i := 0; i < n; i++ {
+				ci := c0 + (sx+i)>>h
+				put565(dst[i*2:], ycbcrTo565(src.Y[yi+i], src.Cb[ci], src.Cr[ci]))
+			}
+		}
+	case *image.Paletted:
+		palette := make([]uint16, 256)
+		for i, c := range src.Palette {
+			r, g, b, _ := c.RGBA()
+			palette[i] = pack565(r, g, b)
+		}
+		return func(dst []byte, sx, sy, n int) {
+			pix := src.Pix[src.PixOffset(sx, sy):]
+			for i := 0; i < n; i++ {
+				put565(dst[i*2:], palette[pix[i]])
+			}
+		}
+	}
+
+	src, off := d.toRGBA(img, r, sp)
+	rgba := d.converter(src, r, r.Min)
+	return func(dst []byte, sx, sy, n int) {
+		rgba(dst, sx+off.X, sy+off.Y, n)
+	}
+}
+
+// toRGBA returns img as an *image.RGBA covering r at sp, and the offset from
+// img coordinates to the returned image's. Images of other types are drawn
+// into d.scratch.
+func (d *Device) toRGBA(img image.Image, r image.Rectangle, sp image.Point) (*image.RGBA, image.Point) {
+	if src, ok := img.(*image.RGBA); ok {
+		return src, image.Point{}
+	}
+	if d.scratch == nil || !r.In(d.scratch.Rect) {
+		d.scratch = image.NewRGBA(d.rect)
+	}
+	draw.Draw(d.scratch, r, img, sp, draw.Src)
+	return d.scratch, r.Min.Sub(sp)
+}
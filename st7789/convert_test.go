@@ -0,0 +1,149 @@
+package st7789_test
+
+import (
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"math/rand"
+	"testing"
+
+	"github.com/rubiojr/go-pirateaudio/st7789"
+	"periph.io/x/conn/v3"
+	"periph.io/x/conn/v3/gpio/gpiotest"
+)
+
+// discard is a bus that drops everything sent to it.
+type discard struct{}
+
+func (discard) String() string       { return "discard" }
+func (discard) Duplex() conn.Duplex  { return conn.Half }
+func (discard) Tx(w, r []byte) error { return nil }
+
+// newDiscarding returns a 240x240 device sending to a discard bus.
+func newDiscarding(tb testing.TB) *st7789.Device {
+	tb.Helper()
+	opts := st7789.DefaultOpts
+	opts.Backlight = nil
+	dev, err := st7789.New(discard{}, &gpiotest.Pin{N: "DC"}, &opts)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return dev
+}
+
+var imageTypes = []string{"RGBA", "NRGBA", "YCbCr", "Paletted", "Gray", "RGBA64", "RGB565"}
+
+// newImage returns an image of type kind filled with random pixels from
+// seed.
+func newImage(r image.Rectangle, kind string, seed int64) image.Image {
+	rnd := rand.New(rand.NewSource(seed))
+	switch kind {
+	case "NRGBA":
+		img := image.NewNRGBA(r)
+		rnd.Read(img.Pix)
+		return img
+	case "YCbCr":
+		img := image.NewYCbCr(r, image.YCbCrSubsampleRatio420)
+		rnd.Read(img.Y)
+		rnd.Read(img.Cb)
+		rnd.Read(img.Cr)
+		return img
+	case "Paletted":
+		img := image.NewPaletted(r, palette.Plan9)
+		rnd.Read(img.Pix)
+		return img
+	case "Gray":
+		img := image.NewGray(r)
+		rnd.Read(img.Pix)
+		return img
+	case "RGB565":
+		img := st7789.NewRGB565Image(r)
+		rnd.Read(img.Pix)
+		return img
+	case "RGBA64":
+		img := image.NewRGBA64(r)
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				img.Set(x, y, color.RGBA64{uint16(rnd.Uint32()), uint16(rnd.Uint32()), uint16(rnd.Uint32()), 0xFFFF})
+			}
+		}
+		return img
+	}
+	img := image.NewRGBA(r)
+	rnd.Read(img.Pix)
+	return img
+}
+
+func TestBlitConversion(t *testing.T) {
+	dev := newDiscarding(t)
+	for _, kind := range append(imageTypes, "Uniform") {
+		t.Run(kind, func(t *testing.T) {
+			// Smaller than the display and offset, so part of it is clipped
+			// and part of the display is not covered
+			var img image.Image = image.NewUniform(color.NRGBA{200, 100, 50, 128})
+			if kind != "Uniform" {
+				img = newImage(image.Rect(-7, 3, 193, 203), kind, 1)
+			}
+			r := image.Rect(5, 5, 235, 235)
+			sp := image.Pt(-17, 0)
+			dev.Blit(r, img, sp, nil)
+
+			want := image.NewRGBA(r)
+			draw.Draw(want, r, img, sp, draw.Src)
+			for y := r.Min.Y; y < r.Max.Y; y++ {
+				for x := r.Min.X; x < r.Max.X; x++ {
+					var c uint16
+					if (image.Point{x, y}.Sub(r.Min).Add(sp).In(img.Bounds())) {
+						c = st7789.RGBATo565(want.RGBAAt(x, y))
+					}
+					if got := dev.At(x, y).(st7789.RGB565); uint16(got) != c {
+						t.Fatalf("pixel %d,%d: got %#04x, want %#04x", x, y, got, c)
+					}
+				}
+			}
+		})
+	}
+}
+
+// benchDrawRAW draws two frames alternately, so every draw changes every
+// pixel.
+func benchDrawRAW(b *testing.B, dev *st7789.Device, frames [2]image.Image) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := dev.DrawRAW(frames[i%2]); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "fps")
+}
+
+func BenchmarkDrawRAW(b *testing.B) {
+	dev := newDiscarding(b)
+	for _, kind := range imageTypes {
+		b.Run(kind, func(b *testing.B) {
+			frames := [2]image.Image{newImage(dev.Bounds(), kind, 1), newImage(dev.Bounds(), kind, 2)}
+			benchDrawRAW(b, dev, frames)
+		})
+	}
+}
+
+func BenchmarkDrawRAWDither(b *testing.B) {
+	dev := newDiscarding(b)
+	frames := [2]image.Image{newImage(dev.Bounds(), "RGBA", 1), newImage(dev.Bounds(), "RGBA", 2)}
+	for _, dither := range []struct {
+		name   string
+		dither st7789.Dither
+	}{
+		{"Bayer4", st7789.DITHER_BAYER_4},
+		{"Bayer8", st7789.DITHER_BAYER_8},
+		{"FloydSteinberg", st7789.DITHER_FLOYD_STEINBERG},
+	} {
+		b.Run(dither.name, func(b *testing.B) {
+			dev.SetDither(dither.dither)
+			defer dev.SetDither(st7789.DITHER_NONE)
+			benchDrawRAW(b, dev, frames)
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package st7789
+
+import (
+	"image"
+)
+
+// Dither is how colors are reduced to the 16 bits of the display.
+type Dither uint8
+
+const (
+	DITHER_NONE            Dither = 0 // truncate the low bits, fastest
+	DITHER_BAYER_4         Dither = 1 // ordered, with a 4x4 Bayer matrix
+	DITHER_BAYER_8         Dither = 2 // ordered, with an 8x8 Bayer matrix
+	DITHER_FLOYD_STEINBERG Dither = 3 // error diffusion, smoothest, slowest
+)
+
+// SetDither sets how images drawn from now on are reduced to 16-bit colors.
+// RGB565Image pixels are never dithered.
+func (d *Device) SetDither(dither Dither) {
+	d.dither = dither
+}
+
+// Dither returns the dithering set with SetDither or Opts.Dither.
+func (d *Device) Dither() Dither {
+	return d.dither
+}
+
+// bayer4 and bayer8 are the Bayer threshold matrices, row-major.
+var (
+	bayer4 = bayerMatrix(4)
+	bayer8 = bayerMatrix(8)
+)
+
+// bayerMatrix returns the n x n Bayer matrix, n being a power of two.
+func bayerMatrix(n int) []uint8 {
+	m := []uint8{0}
+	for size := 1; size < n; size *= 2 {
+		next := make([]uint8, size*size*4)
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				v := m[y*size+x] * 4
+				next[y*size*2+x] = v
+				next[y*size*2+x+size] = v + 2
+				next[(y+size)*size*2+x] = v + 3
+				next[(y+size)*size*2+x+size] = v + 1
+			}
+		}
+		m = next
+	}
+	return m
+}
+
+// Nearest 5 and 6-bit levels of each 8-bit value, and the 8-bit value the
+// display shows for each level.
+var (
+	near5, near6     [256]uint8
+	expand5, expand6 [64]int32
+)
+
+// For ordered dithering: the level shown at or below each 8-bit value, and
+// how far the value is towards the next level, out of 256.
+var (
+	floor5, floor6 [256]uint8
+	frac5, frac6   [256]uint8
+)
+
+func init() {
+	for v := 0; v < 256; v++ {
+		near5[v] = uint8((v*31 + 127) / 255)
+		near6[v] = uint8((v*63 + 127) / 255)
+	}
+	for l := 0; l < 64; l++ {
+		expand5[l] = int32(l<<3 | l>>2)
+		expand6[l] = int32(l<<2 | l>>4)
+	}
+	levels(floor5[:], frac5[:], expand5[:32])
+	levels(floor6[:], frac6[:], expand6[:])
+}
+
+// levels fills floor and frac for the shown values in expand.
+func levels(floor, frac []uint8, expand []int32) {
+	l := 0
+	for v := 0; v < 256; v++ {
+		for l+1 < len(expand) && expand[l+1] <= int32(v) {
+			l++
+		}
+		floor[v] = uint8(l)
+		if l+1 < len(expand) {
+			frac[v] = uint8((int32(v) - expand[l]) * 256 / (expand[l+1] - expand[l]))
+		}
+	}
+}
+
+// ditherer returns a rowConverter dithering img as set in d.dither. Error
+// diffusion carries the error of a row to the next one, so rows must be
+// converted in order, from top to bottom.
+func (d *Device) ditherer(img image.Image, r image.Rectangle, sp image.Point) rowConverter {
+	src, off := d.toRGBA(img, r, sp)
+	if d.dither == DITHER_FLOYD_STEINBERG {
+		return floydSteinberg(src, off)
+	}
+	m, n := bayer4, 4
+	if d.dither == DITHER_BAYER_8 {
+		m, n = bayer8, 8
+	}
+	// Thresholds out of 256, centered in their share of a level
+	var thr [64]uint8
+	for i, t := range m {
+		thr[i] = uint8((2*int(t) + 1) * 128 / len(m))
+	}
+	return func(dst []byte, sx, sy, w int) {
+		sx, sy = sx+off.X, sy+off.Y
+		pix := src.Pix[src.PixOffset(sx, sy):]
+		row := (sy & (n - 1)) * n
+		for i := 0; i < w; i++ {
+			p := pix[i*4 : i*4+3 : i*4+3]
+			t := thr[row+(sx+i)&(n-1)]
+			r, g, b := floor5[p[0]], floor6[p[1]], floor5[p[2]]
+			if frac5[p[0]] > t {
+				r++
+			}
+			if frac6[p[1]] > t {
+				g++
+			}
+			if frac5[p[2]] > t {
+				b++
+			}
+			put565(dst[i*2:], uint16(r)<<11|uint16(g)<<5|uint16(b))
+		}
+	}
+}
+
+// floydSteinberg returns a rowConverter diffusing the error of each pixel
+// to its unconverted neighbours.
+func floydSteinberg(src *image.RGBA, off image.Point) rowConverter {
+	// Errors times 16 for the current and the next row, 3 channels per
+	// pixel, with a pixel of margin on each side
+	var cur, next []int32
+	return func(dst []byte, sx, sy, w int) {
+		sx, sy = sx+off.X, sy+off.Y
+		if len(cur) != (w+2)*3 {
+			cur = make([]int32, (w+2)*3)
+			next = make([]int32, (w+2)*3)
+		}
+		clear(next)
+		pix := src.Pix[src.PixOffset(sx, sy):]
+		var levels [3]uint8
+		for i := 0; i < w; i++ {
+			p := pix[i*4 : i*4+3 : i*4+3]
+			e := (i + 1) * 3
+			for c := 0; c < 3; c++ {
+				v := (int32(p[c])*16 + cur[e+c] + 8) >> 4
+				v = min(max(v, 0), 255)
+				var shown int32
+				if c == 1 {
+					levels[c] = near6[v]
+					shown = expand6[levels[c]]
+				} else {
+					levels[c] = near5[v]
+					shown = expand5[levels[c]]
+				}
+				err := v - shown
+				cur[e+3+c] += err * 7
+				next[e-3+c] += err * 3
+				next[e+c] += err * 5
+				next[e+3+c] += err
+			}
+			put565(dst[i*2:], uint16(levels[0])<<11|uint16(levels[1])<<5|uint16(levels[2]))
+		}
+		cur, next = next, cur
+	}
+}
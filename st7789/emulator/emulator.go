@@ -0,0 +1,391 @@
+// Package emulator provides an in-memory ST7789 controller, so the st7789
+// driver can run without a display attached.
+//
+// A Panel decodes the command and data bytes the driver sends, keeps the
+// controller memory (GRAM) and renders what the display would show:
+//
+//	p := emulator.New(240, 240)
+//	opts := st7789.DefaultOpts
+//	opts.Backlight = p.Backlight()
+//	dev, err := st7789.New(p, p.DC(), &opts)
+//	...
+//	img := p.Image()
+package emulator
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"sync"
+
+	"github.com/rubiojr/go-pirateaudio/st7789"
+	"periph.io/x/conn/v3"
+	"periph.io/x/conn/v3/gpio"
+	"periph.io/x/conn/v3/physic"
+)
+
+const (
+	// GRAMWidth and GRAMHeight are the size of the controller memory.
+	GRAMWidth  = 240
+	GRAMHeight = 320
+)
+
+// Command is a command received by the panel, with its parameters. Pixel
+// data sent after RAMWR is not kept, only counted in Pixels.
+type Command struct {
+	Code   uint8
+	Params []byte
+	Pixels int
+}
+
+// Panel emulates an ST7789 controller wired to a width x height panel. It
+// implements conn.Conn, the DC and backlight pins are returned by DC and
+// Backlight.
+type Panel struct {
+	mu     sync.Mutex
+	width  int
+	height int
+	// Where the panel starts in GRAM
+	columnOffset, rowOffset int
+	gram                    []uint16
+	dc                      gpio.Level
+	backlight               gpio.Level
+	log                     []Command
+
+	// Current command and the parameters received so far
+	cmd    uint8
+	params []byte
+	// Pending high byte of a pixel
+	half    byte
+	hasHalf bool
+
+	madctl                  uint8
+	colStart, colEnd        int
+	rowStart, rowEnd        int
+	col, row                int
+	tfa, vsa, bfa, vsp      int
+	ptlStart, ptlEnd        int
+	sleeping, displayOn     bool
+	inverted, idle, partial bool
+}
+
+// New returns a Panel showing width x height pixels, at most GRAMWidth x
+// GRAMHeight, in its power on state. The backlight is on until its pin is
+// driven low.
+func New(width, height int) *Panel {
+	p := &Panel{
+		width:     width,
+		height:    height,
+		gram:      make([]uint16, GRAMWidth*GRAMHeight),
+		backlight: gpio.High,
+	}
+	p.reset()
+	return p
+}
+
+// NewWithOptions returns a Panel of the size given in opts, showing GRAM
+// from opts.ColumnOffset and opts.RowOffset.
+func NewWithOptions(opts *st7789.Opts) *Panel {
+	p := New(int(opts.Width), int(opts.Height))
+	p.columnOffset = int(opts.ColumnOffset)
+	p.rowOffset = int(opts.RowOffset)
+	return p
+}
+
+// reset sets the registers to their values after SWRESET. GRAM is kept.
+func (p *Panel) reset() {
+	p.cmd = st7789.NOP
+	p.params = nil
+	p.hasHalf = false
+	p.madctl = 0
+	p.colStart, p.colEnd = 0, GRAMWidth-1
+	p.rowStart, p.rowEnd = 0, GRAMHeight-1
+	p.tfa, p.vsa, p.bfa, p.vsp = 0, GRAMHeight, 0, 0
+	p.ptlStart, p.ptlEnd = 0, GRAMHeight-1
+	p.sleeping = true
+	p.displayOn = false
+	p.inverted = false
+	p.idle = false
+	p.partial = false
+}
+
+// String implements conn.Conn.
+func (p *Panel) String() string {
+	return fmt.Sprintf("emulator.Panel{%dx%d}", p.width, p.height)
+}
+
+// Duplex implements conn.Conn.
+func (p *Panel) Duplex() conn.Duplex {
+	return conn.Full
+}
+
+// Tx implements conn.Conn. Bytes are commands or data depending on the
+// level of the DC pin. Reads are not supported and return zeros.
+func (p *Panel) Tx(w, r []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	clear(r)
+	for _, b := range w {
+		if p.dc == gpio.Low {
+			p.command(b)
+		} else {
+			p.data(b)
+		}
+	}
+	return nil
+}
+
+func (p *Panel) command(c uint8) {
+	p.cmd = c
+	p.params = nil
+	p.hasHalf = false
+	p.log = append(p.log, Command{Code: c})
+
+	switch c {
+	case st7789.SWRESET:
+		p.reset()
+	case st7789.SLPIN:
+		p.sleeping = true
+	case st7789.SLPOUT:
+		p.sleeping = false
+	case st7789.PTLON:
+		p.partial = true
+	case st7789.NORON:
+		p.partial = false
+	case st7789.INVOFF:
+		p.inverted = false
+	case st7789.INVON:
+		p.inverted = true
+	case st7789.DISPOFF:
+		p.displayOn = false
+	case st7789.DISPON:
+		p.displayOn = true
+	case st7789.IDMOFF:
+		p.idle = false
+	case st7789.IDMON:
+		p.idle = true
+	case st7789.RAMWR:
+		p.col, p.row = p.colStart, p.rowStart
+	}
+}
+
+func (p *Panel) data(b byte) {
+	if len(p.log) == 0 {
+		// Data before any command is ignored
+		return
+	}
+	last := &p.log[len(p.log)-1]
+	if p.cmd == st7789.RAMWR {
+		if !p.hasHalf {
+			p.half, p.hasHalf = b, true
+			return
+		}
+		p.hasHalf = false
+		p.writePixel(uint16(p.half)<<8 | uint16(b))
+		last.Pixels++
+		return
+	}
+
+	p.params = append(p.params, b)
+	last.Params = append(last.Params, b)
+	switch p.cmd {
+	case st7789.CASET:
+		if len(p.params) == 4 {
+			p.colStart, p.colEnd = word(p.params[0:]), word(p.params[2:])
+		}
+	case st7789.RASET:
+		if len(p.params) == 4 {
+			p.rowStart, p.rowEnd = word(p.params[0:]), word(p.params[2:])
+		}
+	case st7789.MADCTL:
+		p.madctl = b
+	case st7789.VSCRDEF:
+		if len(p.params) == 6 {
+			p.tfa, p.vsa, p.bfa = word(p.params[0:]), word(p.params[2:]), word(p.params[4:])
+		}
+	case st7789.VSCSAD:
+		if len(p.params) == 2 {
+			p.vsp = word(p.params)
+		}
+	case st7789.PTLAR:
+		if len(p.params) == 4 {
+			p.ptlStart, p.ptlEnd = word(p.params[0:]), word(p.params[2:])
+		}
+	}
+}
+
+func word(b []byte) int {
+	return int(b[0])<<8 | int(b[1])
+}
+
+// writePixel stores c at the current address and advances it, column first,
+// wrapping around the window set with CASET and RASET.
+func (p *Panel) writePixel(c uint16) {
+	if x, y, ok := p.physical(p.col, p.row); ok {
+		p.gram[y*GRAMWidth+x] = c
+	}
+	p.col++
+	if p.col > p.colEnd {
+		p.col = p.colStart
+		p.row++
+		if p.row > p.rowEnd {
+			p.row = p.rowStart
+		}
+	}
+}
+
+// physical maps a column/row address to a GRAM position, following the
+// MADCTL MY, MX and MV bits.
+func (p *Panel) physical(c, r int) (int, int, bool) {
+	if p.madctl&st7789.MADCTL_MV_REV != 0 {
+		c, r = r, c
+	}
+	if p.madctl&st7789.MADCTL_MX_RL != 0 {
+		c = GRAMWidth - 1 - c
+	}
+	if p.madctl&st7789.MADCTL_MY_BT != 0 {
+		r = GRAMHeight - 1 - r
+	}
+	if c < 0 || c >= GRAMWidth || r < 0 || r >= GRAMHeight {
+		return 0, 0, false
+	}
+	return c, r, true
+}
+
+// scanRow returns the GRAM row shown on display line y, taking vertical
+// scrolling into account.
+func (p *Panel) scanRow(y int) int {
+	if y < p.tfa || y >= p.tfa+p.vsa || p.vsa == 0 {
+		return y
+	}
+	n := (y - p.tfa + p.vsp - p.tfa) % p.vsa
+	if n < 0 {
+		n += p.vsa
+	}
+	return p.tfa + n
+}
+
+// GRAM returns a copy of the whole controller memory.
+func (p *Panel) GRAM() image.Image {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	img := image.NewRGBA(image.Rect(0, 0, GRAMWidth, GRAMHeight))
+	for y := 0; y < GRAMHeight; y++ {
+		for x := 0; x < GRAMWidth; x++ {
+			img.SetRGBA(x, y, toRGBA(p.gram[y*GRAMWidth+x]))
+		}
+	}
+	return img
+}
+
+// Image returns what the display shows: the visible part of GRAM after
+// scrolling, partial and idle modes are applied. Nothing is shown while
+// the display is off or asleep, or the backlight is off. Like the IPS
+// panels the controller is usually paired with, colours are shown as
+// written with inversion on and inverted with it off.
+func (p *Panel) Image() image.Image {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	img := image.NewRGBA(image.Rect(0, 0, p.width, p.height))
+	black := color.RGBA{A: 0xFF}
+	for y := 0; y < p.height; y++ {
+		row := p.scanRow(y + p.rowOffset)
+		for x := 0; x < p.width; x++ {
+			if !p.displayOn || p.sleeping || p.backlight == gpio.Low || (p.partial && !p.inPartialArea(y+p.rowOffset)) {
+				img.SetRGBA(x, y, black)
+				continue
+			}
+			c := p.gram[row*GRAMWidth+x+p.columnOffset]
+			if !p.inverted {
+				c = ^c
+			}
+			rgba := toRGBA(c)
+			if p.idle {
+				rgba = color.RGBA{R: idleLevel(rgba.R), G: idleLevel(rgba.G), B: idleLevel(rgba.B), A: 0xFF}
+			}
+			img.SetRGBA(x, y, rgba)
+		}
+	}
+	return img
+}
+
+// idleLevel keeps only the most significant bit of a colour channel, as
+// idle mode shows 8 colours.
+func idleLevel(v uint8) uint8 {
+	if v&0x80 != 0 {
+		return 0xFF
+	}
+	return 0
+}
+
+func (p *Panel) inPartialArea(y int) bool {
+	if p.ptlStart <= p.ptlEnd {
+		return y >= p.ptlStart && y <= p.ptlEnd
+	}
+	return y >= p.ptlStart || y <= p.ptlEnd
+}
+
+// Commands returns the commands received since the panel was created or
+// ClearCommands was called.
+func (p *Panel) Commands() []Command {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Command(nil), p.log...)
+}
+
+// ClearCommands empties the command log.
+func (p *Panel) ClearCommands() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.log = nil
+}
+
+// MADCTL returns the current memory access control register.
+func (p *Panel) MADCTL() uint8 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.madctl
+}
+
+// DC returns the data/command pin of the panel: low for commands, high for
+// data.
+func (p *Panel) DC() gpio.PinOut {
+	return &pin{p: p, name: "DC", level: &p.dc}
+}
+
+// Backlight returns the pin driving the backlight of the panel.
+func (p *Panel) Backlight() gpio.PinOut {
+	return &pin{p: p, name: "Backlight", level: &p.backlight}
+}
+
+// toRGBA expands a 16-bit colour the same way st7789.Device.At does.
+func toRGBA(c uint16) color.RGBA {
+	r := uint8(c>>11) & 0x1F
+	g := uint8(c>>5) & 0x3F
+	b := uint8(c) & 0x1F
+	return color.RGBA{R: r<<3 | r>>2, G: g<<2 | g>>4, B: b<<3 | b>>2, A: 0xFF}
+}
+
+// pin is an output pin of a Panel, setting level.
+type pin struct {
+	p     *Panel
+	name  string
+	level *gpio.Level
+}
+
+func (o *pin) String() string   { return "emulator." + o.name }
+func (o *pin) Halt() error      { return nil }
+func (o *pin) Name() string     { return o.name }
+func (o *pin) Number() int      { return -1 }
+func (o *pin) Function() string { return "Out" }
+
+func (o *pin) Out(l gpio.Level) error {
+	o.p.mu.Lock()
+	defer o.p.mu.Unlock()
+	*o.level = l
+	return nil
+}
+
+func (o *pin) PWM(gpio.Duty, physic.Frequency) error {
+	return fmt.Errorf("emulator: PWM not supported on %s", o)
+}
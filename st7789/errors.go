@@ -0,0 +1,14 @@
+package st7789
+
+import "errors"
+
+var (
+	// ErrDecode is returned when an image cannot be decoded.
+	ErrDecode = errors.New("st7789: cannot decode image")
+	// ErrBus is returned when a transfer to the display fails.
+	ErrBus = errors.New("st7789: bus error")
+	// ErrOutOfBounds is returned when coordinates fall outside the display.
+	ErrOutOfBounds = errors.New("st7789: outside display area")
+	// ErrUnsupported is returned for settings the display doesn't support.
+	ErrUnsupported = errors.New("st7789: unsupported")
+)
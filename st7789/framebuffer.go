@@ -0,0 +1,148 @@
+package st7789
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+)
+
+// ColorModel implements draw.Image. Colors are stored as RGB565.
+func (d *Device) ColorModel() color.Model {
+	return RGB565Model
+}
+
+// At implements draw.Image. It returns the color stored in the framebuffer,
+// which may differ from what the display shows until Flush is called.
+func (d *Device) At(x, y int) color.Color {
+	if !(image.Point{x, y}.In(d.rect)) {
+		return RGB565(0)
+	}
+	i := d.fbOffset(x, y)
+	return RGB565(uint16(d.fb[i])<<8 | uint16(d.fb[i+1]))
+}
+
+// Set implements draw.Image. It only updates the framebuffer, call Flush to
+// send it to the display.
+func (d *Device) Set(x, y int, c color.Color) {
+	if !(image.Point{x, y}.In(d.rect)) {
+		return
+	}
+	c565 := RGB565Model.Convert(c).(RGB565)
+	i := d.fbOffset(x, y)
+	d.fb[i] = uint8(c565 >> 8)
+	d.fb[i+1] = uint8(c565)
+}
+
+// Flush sends the whole framebuffer to the display.
+func (d *Device) Flush() error {
+	return d.FlushRegion(d.rect)
+}
+
+func (d *Device) fbOffset(x, y int) int {
+	return (y*d.rect.Dx() + x) * 2
+}
+
+// fill sets every pixel of r in the framebuffer to c565.
+func (d *Device) fill(r image.Rectangle, c565 uint16) {
+	c1 := uint8(c565 >> 8)
+	c2 := uint8(c565)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		row := d.fb[d.fbOffset(r.Min.X, y):d.fbOffset(r.Max.X, y)]
+		for i := 0; i < len(row); i += 2 {
+			row[i] = c1
+			row[i+1] = c2
+		}
+	}
+}
+
+// blit copies img into the framebuffer area r, reading from sp onwards.
+func (d *Device) blit(r image.Rectangle, img image.Image, sp image.Point) {
+	d.Blit(r, img, sp, nil)
+}
+
+// Blit copies img into the framebuffer area r, reading from sp onwards,
+// without sending anything to the display. Parts of r outside img are set
+// to black. If mark is not nil it is called once per row with the span of
+// pixels whose value changed.
+func (d *Device) Blit(r image.Rectangle, img image.Image, sp image.Point, mark func(image.Rectangle)) {
+	clipped := r.Intersect(d.rect)
+	if clipped.Empty() {
+		return
+	}
+	sp = sp.Add(clipped.Min.Sub(r.Min))
+	r = clipped
+
+	// The part of r covered by img, and where it is read from
+	inside := img.Bounds().Add(r.Min.Sub(sp)).Intersect(r)
+	var convert rowConverter
+	if !inside.Empty() {
+		convert = d.converter(img, inside, sp.Add(inside.Min.Sub(r.Min)))
+	}
+	if cap(d.row) < r.Dx()*2 {
+		d.row = make([]byte, d.rect.Dx()*2)
+	}
+	row := d.row[:r.Dx()*2]
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		clear(row)
+		if convert != nil && y >= inside.Min.Y && y < inside.Max.Y {
+			convert(row[(inside.Min.X-r.Min.X)*2:], sp.X+inside.Min.X-r.Min.X, sp.Y+y-r.Min.Y, inside.Dx())
+		}
+		fbRow := d.fb[d.fbOffset(r.Min.X, y):d.fbOffset(r.Max.X, y)]
+		if bytes.Equal(fbRow, row) {
+			continue
+		}
+		first, last := 0, len(row)-2
+		for fbRow[first] == row[first] && fbRow[first+1] == row[first+1] {
+			first += 2
+		}
+		for fbRow[last] == row[last] && fbRow[last+1] == row[last+1] {
+			last -= 2
+		}
+		copy(fbRow[first:last+2], row[first:last+2])
+		if mark != nil {
+			mark(image.Rect(r.Min.X+first/2, y, r.Min.X+last/2+1, y+1))
+		}
+	}
+}
+
+// FlushRegion sends the framebuffer area r to the display, leaving the rest
+// of the display untouched.
+func (d *Device) FlushRegion(r image.Rectangle) error {
+	r = r.Intersect(d.rect)
+	if r.Empty() {
+		return nil
+	}
+	if err := d.setWindow(r); err != nil {
+		return err
+	}
+	buf := d.tx[:0]
+	defer func() { d.tx = buf[:0] }()
+	if d.softwareRotation {
+		pr := d.panelRect(r)
+		for py := pr.Min.Y; py < pr.Max.Y; py++ {
+			for px := pr.Min.X; px < pr.Max.X; px++ {
+				i := d.fbOffset(d.fromPanel(d.rotation, px, py))
+				buf = append(buf, d.fb[i], d.fb[i+1])
+			}
+		}
+		return d.sendChunked(buf)
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		buf = append(buf, d.fb[d.fbOffset(r.Min.X, y):d.fbOffset(r.Max.X, y)]...)
+	}
+	return d.sendChunked(buf)
+}
+
+// rgb565ToRGBA expands a 16-bit color to color.RGBA, replicating the high
+// bits into the low ones so that white stays white.
+func rgb565ToRGBA(c uint16) color.RGBA {
+	r := uint8(c>>11) & 0x1F
+	g := uint8(c>>5) & 0x3F
+	b := uint8(c) & 0x1F
+	return color.RGBA{
+		R: r<<3 | r>>2,
+		G: g<<2 | g>>4,
+		B: b<<3 | b>>2,
+		A: 0xFF,
+	}
+}
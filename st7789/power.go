@@ -0,0 +1,166 @@
+package st7789
+
+import (
+	"fmt"
+	"time"
+
+	"periph.io/x/conn/v3/gpio"
+)
+
+type PowerMode uint8
+
+const (
+	POWER_NORMAL       PowerMode = 0 // full display, 65K colours
+	POWER_PARTIAL      PowerMode = 1 // only the rows set with SetPartialArea are shown
+	POWER_IDLE         PowerMode = 2 // full display, 8 colours
+	POWER_PARTIAL_IDLE PowerMode = 3 // partial and idle combined
+	POWER_SLEEP        PowerMode = 4 // display and backlight off, memory retained
+)
+
+const (
+	// sleepOutDelay is how long the controller needs after SLPOUT before it
+	// accepts SLPIN again and its supply voltages are stable.
+	sleepOutDelay = 120 * time.Millisecond
+	// sleepInDelay is how long the controller needs after SLPIN before it
+	// accepts new commands.
+	sleepInDelay = 5 * time.Millisecond
+	// sleepInOutDelay is how long the controller needs after SLPIN before it
+	// accepts SLPOUT.
+	sleepInOutDelay = 120 * time.Millisecond
+)
+
+// PowerMode returns the current power mode.
+func (d *Device) PowerMode() PowerMode {
+	return d.powerMode
+}
+
+// SetPartialArea sets the rows, from start to end inclusive, shown in
+// POWER_PARTIAL and POWER_PARTIAL_IDLE modes. Rows follow the rotation,
+// like DefineScrollArea. It returns ErrUnsupported with ROTATION_90 and
+// ROTATION_270.
+func (d *Device) SetPartialArea(start, end int) error {
+	if err := d.checkPartialRotation(d.rotation); err != nil {
+		return err
+	}
+	if start < 0 || end < start || end >= d.rect.Dy() {
+		return fmt.Errorf("%w: partial area %d-%d", ErrOutOfBounds, start, end)
+	}
+	d.partialStart = start
+	d.partialEnd = end
+	if d.partial() {
+		return d.sendPartialArea()
+	}
+	return nil
+}
+
+// partial reports whether the display is in a partial mode.
+func (d *Device) partial() bool {
+	return d.powerMode == POWER_PARTIAL || d.powerMode == POWER_PARTIAL_IDLE
+}
+
+// checkPartialRotation returns an error if a partial mode is, or is about
+// to be, used with rotation, which turns panel rows into framebuffer
+// columns.
+func (d *Device) checkPartialRotation(rotation Rotation) error {
+	if rotation == ROTATION_90 || rotation == ROTATION_270 {
+		return fmt.Errorf("%w: partial mode with rotation %d", ErrUnsupported, rotation)
+	}
+	return nil
+}
+
+// SetPowerMode switches the display to mode. Waking up from POWER_SLEEP
+// waits for the controller to be ready and turns the backlight back on.
+func (d *Device) SetPowerMode(mode PowerMode) error {
+	if mode > POWER_SLEEP {
+		return fmt.Errorf("%w: power mode %d", ErrUnsupported, mode)
+	}
+	if mode == d.powerMode {
+		return nil
+	}
+	if mode == POWER_PARTIAL || mode == POWER_PARTIAL_IDLE {
+		if err := d.checkPartialRotation(d.rotation); err != nil {
+			return err
+		}
+	}
+
+	if mode == POWER_SLEEP {
+		d.waitSleepOut()
+		if err := d.Command(DISPOFF); err != nil {
+			return err
+		}
+		if err := d.Command(SLPIN); err != nil {
+			return err
+		}
+		d.sleepIn = time.Now()
+		time.Sleep(sleepInDelay)
+		d.powerMode = mode
+		return d.setBacklight(gpio.Low)
+	}
+
+	if d.powerMode == POWER_SLEEP {
+		d.waitSleepIn()
+		if err := d.Command(SLPOUT); err != nil {
+			return err
+		}
+		d.sleepOut = time.Now()
+		time.Sleep(sleepOutDelay)
+		if err := d.Command(DISPON); err != nil {
+			return err
+		}
+		if err := d.setBacklight(gpio.High); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	switch mode {
+	case POWER_NORMAL, POWER_IDLE:
+		err = d.Command(NORON)
+	case POWER_PARTIAL, POWER_PARTIAL_IDLE:
+		if err = d.sendPartialArea(); err == nil {
+			err = d.Command(PTLON)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if mode == POWER_IDLE || mode == POWER_PARTIAL_IDLE {
+		err = d.Command(IDMON)
+	} else {
+		err = d.Command(IDMOFF)
+	}
+	if err != nil {
+		return err
+	}
+	d.powerMode = mode
+	return nil
+}
+
+// sendPartialArea sends the partial area. Panel rows run bottom to top with
+// ROTATION_180, so the rows are flipped, and start at the row offset.
+func (d *Device) sendPartialArea() error {
+	start, end := d.partialStart, d.partialEnd
+	if d.rotation == ROTATION_180 {
+		start, end = int(d.height)-1-end, int(d.height)-1-start
+	}
+	start += d.rowOffset
+	end += d.rowOffset
+	return d.cmd(PTLAR,
+		byte(start>>8), byte(start&0xFF),
+		byte(end>>8), byte(end&0xFF),
+	)
+}
+
+// waitSleepOut blocks until SLPIN can be sent after the last SLPOUT.
+func (d *Device) waitSleepOut() {
+	if wait := sleepOutDelay - time.Since(d.sleepOut); wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+// waitSleepIn blocks until SLPOUT can be sent after the last SLPIN.
+func (d *Device) waitSleepIn() {
+	if wait := sleepInOutDelay - time.Since(d.sleepIn); wait > 0 {
+		time.Sleep(wait)
+	}
+}
@@ -0,0 +1,213 @@
+package st7789_test
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rubiojr/go-pirateaudio/st7789"
+	"github.com/rubiojr/go-pirateaudio/st7789/emulator"
+)
+
+// codes returns the codes of the commands received by p since the last
+// call, and clears them.
+func codes(p *emulator.Panel) []uint8 {
+	var cs []uint8
+	for _, c := range p.Commands() {
+		cs = append(cs, c.Code)
+	}
+	p.ClearCommands()
+	return cs
+}
+
+// checkPartial checks that only the display rows from start to end of img
+// are shown, with the display rotated by rotation.
+func checkPartial(t *testing.T, dev *st7789.Device, p *emulator.Panel, rotation st7789.Rotation, img *st7789.RGB565Image, start, end int) {
+	t.Helper()
+	r := dev.Bounds()
+	shown := p.Image()
+	for y := 0; y < r.Dy(); y++ {
+		for x := 0; x < r.Dx(); x++ {
+			px, py := panelPos(rotation, r.Dx(), r.Dy(), x, y)
+			got := st7789.RGB565Model.Convert(shown.At(px, py)).(st7789.RGB565)
+			want := img.RGB565At(x, y)
+			if y < start || y > end {
+				want = 0
+			}
+			if got != want {
+				t.Fatalf("pixel %d,%d is %#04x, want %#04x with rows %d-%d shown", x, y, got, want, start, end)
+			}
+		}
+	}
+}
+
+func TestPartialArea(t *testing.T) {
+	const start, end = 30, 99
+	for _, size := range []image.Point{{240, 240}, {240, 320}} {
+		for _, rotation := range []st7789.Rotation{st7789.ROTATION_NONE, st7789.ROTATION_180} {
+			for _, software := range []bool{false, true} {
+				name := fmt.Sprintf("%dx%d/rotation%d/software=%v", size.X, size.Y, rotation, software)
+				t.Run(name, func(t *testing.T) {
+					t.Parallel()
+					dev, p := newEmulated(t, size.X, size.Y, rotation, software)
+					img := pattern(dev.Bounds())
+					if err := dev.DrawRAW(img); err != nil {
+						t.Fatal(err)
+					}
+					if err := dev.SetPartialArea(start, end); err != nil {
+						t.Fatal(err)
+					}
+					if err := dev.SetPowerMode(st7789.POWER_PARTIAL); err != nil {
+						t.Fatal(err)
+					}
+					checkPartial(t, dev, p, rotation, img, start, end)
+
+					// The area keeps its display rows when turned upside down
+					rotation := rotation ^ st7789.ROTATION_180
+					if err := dev.SetRotation(rotation); err != nil {
+						t.Fatal(err)
+					}
+					if err := dev.DrawRAW(img); err != nil {
+						t.Fatal(err)
+					}
+					checkPartial(t, dev, p, rotation, img, start, end)
+
+					if err := dev.SetPowerMode(st7789.POWER_NORMAL); err != nil {
+						t.Fatal(err)
+					}
+					checkPartial(t, dev, p, rotation, img, 0, size.Y-1)
+				})
+			}
+		}
+	}
+}
+
+func TestPartialAreaRotated(t *testing.T) {
+	for _, rotation := range []st7789.Rotation{st7789.ROTATION_90, st7789.ROTATION_270} {
+		t.Run(fmt.Sprint(rotation), func(t *testing.T) {
+			t.Parallel()
+			dev, _ := newEmulated(t, 240, 320, rotation, false)
+			if err := dev.SetPartialArea(0, 9); !errors.Is(err, st7789.ErrUnsupported) {
+				t.Errorf("SetPartialArea returned %v, want ErrUnsupported", err)
+			}
+			if err := dev.SetPowerMode(st7789.POWER_PARTIAL_IDLE); !errors.Is(err, st7789.ErrUnsupported) {
+				t.Errorf("SetPowerMode(POWER_PARTIAL_IDLE) returned %v, want ErrUnsupported", err)
+			}
+
+			if err := dev.SetRotation(st7789.ROTATION_NONE); err != nil {
+				t.Fatal(err)
+			}
+			if err := dev.SetPowerMode(st7789.POWER_PARTIAL); err != nil {
+				t.Fatal(err)
+			}
+			if err := dev.SetRotation(rotation); !errors.Is(err, st7789.ErrUnsupported) {
+				t.Errorf("SetRotation in partial mode returned %v, want ErrUnsupported", err)
+			}
+			if b := dev.Bounds(); b.Dx() != 240 || b.Dy() != 320 {
+				t.Errorf("rotated to bounds %v", b)
+			}
+		})
+	}
+}
+
+func TestPowerModes(t *testing.T) {
+	dev, p := newEmulated(t, 240, 240, st7789.ROTATION_NONE, false)
+	if err := dev.DrawRAW(pattern(dev.Bounds())); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetPartialArea(10, 19); err != nil {
+		t.Fatal(err)
+	}
+	p.ClearCommands()
+
+	for _, step := range []struct {
+		mode  st7789.PowerMode
+		codes []uint8
+	}{
+		{st7789.POWER_PARTIAL, []uint8{st7789.PTLAR, st7789.PTLON, st7789.IDMOFF}},
+		{st7789.POWER_IDLE, []uint8{st7789.NORON, st7789.IDMON}},
+		{st7789.POWER_PARTIAL_IDLE, []uint8{st7789.PTLAR, st7789.PTLON, st7789.IDMON}},
+		{st7789.POWER_SLEEP, []uint8{st7789.DISPOFF, st7789.SLPIN}},
+		{st7789.POWER_NORMAL, []uint8{st7789.SLPOUT, st7789.DISPON, st7789.NORON, st7789.IDMOFF}},
+	} {
+		if err := dev.SetPowerMode(step.mode); err != nil {
+			t.Fatal(err)
+		}
+		if dev.PowerMode() != step.mode {
+			t.Errorf("power mode %d, want %d", dev.PowerMode(), step.mode)
+		}
+		if cs := codes(p); !slices.Equal(cs, step.codes) {
+			t.Errorf("mode %d: sent commands %#02x, want %#02x", step.mode, cs, step.codes)
+		}
+
+		shown := p.Image()
+		for y := 0; y < 240; y++ {
+			c := shown.At(0, y).(color.RGBA)
+			black := c == color.RGBA{A: 0xFF}
+			switch step.mode {
+			case st7789.POWER_SLEEP:
+				if !black {
+					t.Fatalf("asleep, row %d shows %v", y, c)
+				}
+			case st7789.POWER_PARTIAL, st7789.POWER_PARTIAL_IDLE:
+				if (y < 10 || y > 19) && !black {
+					t.Fatalf("mode %d, row %d outside the partial area shows %v", step.mode, y, c)
+				}
+			}
+			if step.mode == st7789.POWER_IDLE || step.mode == st7789.POWER_PARTIAL_IDLE {
+				for _, v := range []uint8{c.R, c.G, c.B} {
+					if v != 0 && v != 0xFF {
+						t.Fatalf("mode %d, row %d shows %v, want one of 8 colors", step.mode, y, c)
+					}
+				}
+			}
+		}
+	}
+
+	if err := dev.SetPowerMode(st7789.PowerMode(9)); !errors.Is(err, st7789.ErrUnsupported) {
+		t.Errorf("unknown power mode returned %v, want ErrUnsupported", err)
+	}
+	if cs := codes(p); len(cs) != 0 {
+		t.Errorf("unknown power mode sent %#02x", cs)
+	}
+}
+
+func TestPowerModeDelays(t *testing.T) {
+	const delay = 120 * time.Millisecond
+	dev, _ := newEmulated(t, 240, 240, st7789.ROTATION_NONE, false)
+
+	// SLPOUT was sent while initializing, more than 120ms ago
+	start := time.Now()
+	if err := dev.SetPowerMode(st7789.POWER_SLEEP); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d >= delay {
+		t.Errorf("going to sleep took %v, want no wait for SLPOUT", d)
+	}
+
+	// SLPOUT right after SLPIN waits for it, and then for the controller
+	start = time.Now()
+	if err := dev.SetPowerMode(st7789.POWER_NORMAL); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 2*delay-10*time.Millisecond {
+		t.Errorf("waking up right after sleeping took %v, want at least %v", d, 2*delay-10*time.Millisecond)
+	}
+
+	// After a while asleep, only the controller is waited for
+	if err := dev.SetPowerMode(st7789.POWER_SLEEP); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(delay)
+	start = time.Now()
+	if err := dev.SetPowerMode(st7789.POWER_NORMAL); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < delay || d >= 2*delay-10*time.Millisecond {
+		t.Errorf("waking up took %v, want about %v", d, delay)
+	}
+}
@@ -209,5 +209,5 @@ func defaultVRHSet() uint8 {
 }
 
 func verticalScrollOffset(offset int) []byte {
-	return []byte{0x00, uint8(offset)}
+	return []byte{uint8(offset >> 8), uint8(offset)}
 }
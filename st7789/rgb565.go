@@ -0,0 +1,117 @@
+package st7789
+
+import (
+	"image"
+	"image/color"
+)
+
+// RGB565 is a 16-bit color as stored by the display (bits r:5, g:6, b:5).
+type RGB565 uint16
+
+// RGBA implements color.Color, replicating the high bits of each channel
+// into the low ones so that white stays white.
+func (c RGB565) RGBA() (r, g, b, a uint32) {
+	rgba := rgb565ToRGBA(uint16(c))
+	return rgba.RGBA()
+}
+
+// RGB565Model converts colors to RGB565. Translucent colors are composed
+// over black.
+var RGB565Model color.Model = color.ModelFunc(rgb565Model)
+
+func rgb565Model(c color.Color) color.Color {
+	if c, ok := c.(RGB565); ok {
+		return c
+	}
+	r, g, b, _ := c.RGBA()
+	return RGB565(pack565(r, g, b))
+}
+
+// RGB565Image is an in-memory image whose pixels are stored in the byte
+// order sent to the display: big-endian RGB565. Blit copies its rows
+// without any conversion.
+type RGB565Image struct {
+	// Pix holds the pixels, 2 bytes per pixel, high byte first. The pixel
+	// at (x, y) starts at Pix[(y-Rect.Min.Y)*Stride + (x-Rect.Min.X)*2].
+	Pix []uint8
+	// Stride is the Pix stride (in bytes) between vertically adjacent pixels.
+	Stride int
+	// Rect is the image's bounds.
+	Rect image.Rectangle
+}
+
+// NewRGB565Image returns a black RGB565Image with the given bounds.
+func NewRGB565Image(r image.Rectangle) *RGB565Image {
+	return &RGB565Image{
+		Pix:    make([]uint8, r.Dx()*r.Dy()*2),
+		Stride: r.Dx() * 2,
+		Rect:   r,
+	}
+}
+
+// ColorModel implements image.Image.
+func (p *RGB565Image) ColorModel() color.Model {
+	return RGB565Model
+}
+
+// Bounds implements image.Image.
+func (p *RGB565Image) Bounds() image.Rectangle {
+	return p.Rect
+}
+
+// At implements image.Image.
+func (p *RGB565Image) At(x, y int) color.Color {
+	return p.RGB565At(x, y)
+}
+
+// RGB565At returns the color of the pixel at (x, y), or black outside the
+// bounds.
+func (p *RGB565Image) RGB565At(x, y int) RGB565 {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return 0
+	}
+	i := p.PixOffset(x, y)
+	return RGB565(uint16(p.Pix[i])<<8 | uint16(p.Pix[i+1]))
+}
+
+// PixOffset returns the index of the first element of Pix that corresponds
+// to the pixel at (x, y).
+func (p *RGB565Image) PixOffset(x, y int) int {
+	return (y-p.Rect.Min.Y)*p.Stride + (x-p.Rect.Min.X)*2
+}
+
+// Set implements draw.Image.
+func (p *RGB565Image) Set(x, y int, c color.Color) {
+	p.SetRGB565(x, y, RGB565Model.Convert(c).(RGB565))
+}
+
+// SetRGB565 sets the pixel at (x, y), if it is within the bounds.
+func (p *RGB565Image) SetRGB565(x, y int, c RGB565) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	i := p.PixOffset(x, y)
+	p.Pix[i] = uint8(c >> 8)
+	p.Pix[i+1] = uint8(c)
+}
+
+// SubImage returns an image representing the portion of p visible through
+// r. The returned value shares pixels with p.
+func (p *RGB565Image) SubImage(r image.Rectangle) image.Image {
+	r = r.Intersect(p.Rect)
+	if r.Empty() {
+		return &RGB565Image{}
+	}
+	i := p.PixOffset(r.Min.X, r.Min.Y)
+	return &RGB565Image{
+		Pix:    p.Pix[i:],
+		Stride: p.Stride,
+		Rect:   r,
+	}
+}
+
+// Opaque implements the optional Opaque method of image.Image. RGB565 has
+// no alpha channel.
+func (p *RGB565Image) Opaque() bool {
+	return true
+}
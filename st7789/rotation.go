@@ -0,0 +1,162 @@
+package st7789
+
+import "image"
+
+// gramCols is the number of columns in the controller memory. The panel
+// shows width columns and height rows of it, starting at the column and
+// row offsets.
+const gramCols = 240
+
+// SetRotation changes the rotation of the device (clock-wise). The bounds
+// of the device follow the rotation, swapping width and height for
+// ROTATION_90 and ROTATION_270. The framebuffer is rotated too, so it keeps
+// matching what the display shows. ROTATION_90 and ROTATION_270 return an
+// error while a scroll area is defined, see DefineScrollArea.
+func (d *Device) SetRotation(rotation Rotation) error {
+	rotation %= 4
+	if d.scrollHeight != 0 {
+		if err := d.checkScrollRotation(rotation); err != nil {
+			return err
+		}
+	}
+	if d.partial() {
+		if err := d.checkPartialRotation(rotation); err != nil {
+			return err
+		}
+	}
+	old := d.rotation
+	d.rotation = rotation
+	if err := d.cmd(MADCTL, d.madctl()); err != nil {
+		d.rotation = old
+		return err
+	}
+	d.rotateFramebuffer(old)
+
+	// The scroll area keeps its framebuffer rows, which are other panel
+	// rows after turning the display upside down
+	if d.scrollHeight != 0 {
+		if err := d.sendScrollArea(); err != nil {
+			return err
+		}
+	}
+	// And so does the partial area
+	if d.partial() {
+		return d.sendPartialArea()
+	}
+	return nil
+}
+
+// SoftwareRotation reports whether pixels are rotated by the driver
+// instead of the controller, see Opts.SoftwareRotation.
+func (d *Device) SoftwareRotation() bool {
+	return d.softwareRotation
+}
+
+// madctl returns the memory access control value for the current
+// rotation. Each value makes the controller map a row-major stream of the
+// rotated framebuffer to the right place in memory, given the offsets
+// returned by addressOffset.
+func (d *Device) madctl() uint8 {
+	var madctl uint8
+	if !d.softwareRotation {
+		switch d.rotation {
+		case ROTATION_90:
+			madctl = MADCTL_MX_RL | MADCTL_MV_REV
+		case ROTATION_180:
+			madctl = MADCTL_MX_RL | MADCTL_MY_BT
+		case ROTATION_270:
+			madctl = MADCTL_MY_BT | MADCTL_MV_REV
+		}
+	}
+	if d.isBGR {
+		madctl |= MADCTL_BGR
+	}
+	return madctl
+}
+
+// addressOffset returns what has to be added to framebuffer columns and
+// rows to get the column and row addresses for the current rotation. The
+// mirrored directions count from the end of the memory, so the offsets are
+// those of the far edges of the panel.
+func (d *Device) addressOffset() (int, int) {
+	// Memory columns and rows before and after the panel
+	left, right := d.columnOffset, gramCols-int(d.width)-d.columnOffset
+	top, bottom := d.rowOffset, gramRows-int(d.height)-d.rowOffset
+	if d.softwareRotation {
+		return left, top
+	}
+	switch d.rotation {
+	case ROTATION_90:
+		return top, right
+	case ROTATION_180:
+		return right, bottom
+	case ROTATION_270:
+		return bottom, left
+	}
+	return left, top
+}
+
+// rotatedBounds returns the bounds of the framebuffer for rotation.
+func (d *Device) rotatedBounds(rotation Rotation) image.Rectangle {
+	if rotation == ROTATION_90 || rotation == ROTATION_270 {
+		return image.Rect(0, 0, int(d.height), int(d.width))
+	}
+	return image.Rect(0, 0, int(d.width), int(d.height))
+}
+
+// toPanel returns the panel position of framebuffer pixel x, y when the
+// framebuffer is rotated by rotation.
+func (d *Device) toPanel(rotation Rotation, x, y int) (int, int) {
+	w, h := int(d.width), int(d.height)
+	switch rotation {
+	case ROTATION_90:
+		return w - 1 - y, x
+	case ROTATION_180:
+		return w - 1 - x, h - 1 - y
+	case ROTATION_270:
+		return y, h - 1 - x
+	}
+	return x, y
+}
+
+// fromPanel is the inverse of toPanel.
+func (d *Device) fromPanel(rotation Rotation, px, py int) (int, int) {
+	w, h := int(d.width), int(d.height)
+	switch rotation {
+	case ROTATION_90:
+		return py, w - 1 - px
+	case ROTATION_180:
+		return w - 1 - px, h - 1 - py
+	case ROTATION_270:
+		return h - 1 - py, px
+	}
+	return px, py
+}
+
+// panelRect returns the panel area showing framebuffer area r.
+func (d *Device) panelRect(r image.Rectangle) image.Rectangle {
+	x0, y0 := d.toPanel(d.rotation, r.Min.X, r.Min.Y)
+	x1, y1 := d.toPanel(d.rotation, r.Max.X-1, r.Max.Y-1)
+	return image.Rect(min(x0, x1), min(y0, y1), max(x0, x1)+1, max(y0, y1)+1)
+}
+
+// rotateFramebuffer lays the framebuffer out for the current rotation,
+// from its layout for old, keeping each pixel at the same panel position.
+func (d *Device) rotateFramebuffer(old Rotation) {
+	d.rect = d.rotatedBounds(d.rotation)
+	if old == d.rotation {
+		return
+	}
+	oldStride := d.rotatedBounds(old).Dx()
+	fb := make([]byte, len(d.fb))
+	for y := 0; y < d.rect.Dy(); y++ {
+		for x := 0; x < d.rect.Dx(); x++ {
+			px, py := d.toPanel(d.rotation, x, y)
+			ox, oy := d.fromPanel(old, px, py)
+			i := d.fbOffset(x, y)
+			j := (oy*oldStride + ox) * 2
+			fb[i], fb[i+1] = d.fb[j], d.fb[j+1]
+		}
+	}
+	d.fb = fb
+}
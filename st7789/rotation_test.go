@@ -0,0 +1,243 @@
+package st7789_test
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"testing"
+
+	"github.com/rubiojr/go-pirateaudio/st7789"
+	"github.com/rubiojr/go-pirateaudio/st7789/emulator"
+	"periph.io/x/conn/v3/gpio/gpiotest"
+)
+
+// newEmulated returns a device driving an emulated width x height panel.
+func newEmulated(t *testing.T, width, height int, rotation st7789.Rotation, software bool) (*st7789.Device, *emulator.Panel) {
+	t.Helper()
+	opts := st7789.DefaultOpts
+	opts.Width = int16(width)
+	opts.Height = int16(height)
+	opts.Rotation = rotation
+	opts.SoftwareRotation = software
+	return newEmulatedWithOptions(t, opts)
+}
+
+// newEmulatedWithOptions returns a device driving an emulated panel set up
+// as in opts.
+func newEmulatedWithOptions(t *testing.T, opts st7789.Opts) (*st7789.Device, *emulator.Panel) {
+	t.Helper()
+	p := emulator.NewWithOptions(&opts)
+	opts.Backlight = p.Backlight()
+	dev, err := st7789.New(p, p.DC(), &opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dev, p
+}
+
+// pattern returns an image where every pixel has a color depending on its
+// position, so any flip or transposition shows.
+func pattern(r image.Rectangle) *st7789.RGB565Image {
+	img := st7789.NewRGB565Image(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetRGB565(x, y, st7789.RGB565(x*331+y*7919+1))
+		}
+	}
+	return img
+}
+
+// panelPos returns where pixel x, y of an image rotated clock-wise by
+// rotation is shown on a width x height panel.
+func panelPos(rotation st7789.Rotation, width, height, x, y int) (int, int) {
+	switch rotation {
+	case st7789.ROTATION_90:
+		return width - 1 - y, x
+	case st7789.ROTATION_180:
+		return width - 1 - x, height - 1 - y
+	case st7789.ROTATION_270:
+		return y, height - 1 - x
+	}
+	return x, y
+}
+
+var sizes = []image.Point{{240, 240}, {240, 320}, {135, 240}}
+
+var rotations = []st7789.Rotation{
+	st7789.ROTATION_NONE,
+	st7789.ROTATION_90,
+	st7789.ROTATION_180,
+	st7789.ROTATION_270,
+}
+
+func TestRotationGRAM(t *testing.T) {
+	for _, size := range sizes {
+		for _, rotation := range rotations {
+			for _, software := range []bool{false, true} {
+				name := fmt.Sprintf("%dx%d/rotation%d/software=%v", size.X, size.Y, rotation, software)
+				t.Run(name, func(t *testing.T) {
+					t.Parallel()
+					dev, p := newEmulated(t, size.X, size.Y, rotation, software)
+					b := dev.Bounds()
+					if rotation == st7789.ROTATION_90 || rotation == st7789.ROTATION_270 {
+						if b.Dx() != size.Y || b.Dy() != size.X {
+							t.Fatalf("bounds %v, want %dx%d", b, size.Y, size.X)
+						}
+					}
+					img := pattern(b)
+					if err := dev.DrawRAW(img); err != nil {
+						t.Fatal(err)
+					}
+					gram := p.GRAM()
+					for y := b.Min.Y; y < b.Max.Y; y++ {
+						for x := b.Min.X; x < b.Max.X; x++ {
+							px, py := panelPos(rotation, size.X, size.Y, x, y)
+							got := st7789.RGB565Model.Convert(gram.At(px, py)).(st7789.RGB565)
+							if want := img.RGB565At(x, y); got != want {
+								t.Fatalf("pixel %d,%d at panel %d,%d: got %#04x, want %#04x", x, y, px, py, got, want)
+							}
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+// addressOffset returns the column and row offsets of the window of a
+// width x height panel rotated by the controller.
+func addressOffset(rotation st7789.Rotation, width, height int) (int, int) {
+	switch rotation {
+	case st7789.ROTATION_90:
+		return 0, emulator.GRAMWidth - width
+	case st7789.ROTATION_180:
+		return emulator.GRAMWidth - width, emulator.GRAMHeight - height
+	case st7789.ROTATION_270:
+		return emulator.GRAMHeight - height, 0
+	}
+	return 0, 0
+}
+
+func TestRotationWindow(t *testing.T) {
+	r := image.Rect(10, 20, 30, 25)
+	for _, size := range sizes {
+		for _, rotation := range rotations {
+			for _, software := range []bool{false, true} {
+				name := fmt.Sprintf("%dx%d/rotation%d/software=%v", size.X, size.Y, rotation, software)
+				t.Run(name, func(t *testing.T) {
+					t.Parallel()
+					dev, p := newEmulated(t, size.X, size.Y, rotation, software)
+					p.ClearCommands()
+					if err := dev.FlushRegion(r); err != nil {
+						t.Fatal(err)
+					}
+
+					var want image.Rectangle
+					if software {
+						x0, y0 := panelPos(rotation, size.X, size.Y, r.Min.X, r.Min.Y)
+						x1, y1 := panelPos(rotation, size.X, size.Y, r.Max.X-1, r.Max.Y-1)
+						want = image.Rect(min(x0, x1), min(y0, y1), max(x0, x1)+1, max(y0, y1)+1)
+					} else {
+						want = r.Add(image.Pt(addressOffset(rotation, size.X, size.Y)))
+					}
+					cmds := p.Commands()
+					if len(cmds) != 3 {
+						t.Fatalf("got %d commands, want CASET, RASET and RAMWR", len(cmds))
+					}
+					caset := []byte{byte(want.Min.X >> 8), byte(want.Min.X), byte((want.Max.X - 1) >> 8), byte(want.Max.X - 1)}
+					raset := []byte{byte(want.Min.Y >> 8), byte(want.Min.Y), byte((want.Max.Y - 1) >> 8), byte(want.Max.Y - 1)}
+					if c := cmds[0]; c.Code != st7789.CASET || string(c.Params) != string(caset) {
+						t.Errorf("got command %#02x %v, want CASET %v", c.Code, c.Params, caset)
+					}
+					if c := cmds[1]; c.Code != st7789.RASET || string(c.Params) != string(raset) {
+						t.Errorf("got command %#02x %v, want RASET %v", c.Code, c.Params, raset)
+					}
+					if c := cmds[2]; c.Code != st7789.RAMWR || c.Pixels != r.Dx()*r.Dy() {
+						t.Errorf("got command %#02x with %d pixels, want RAMWR with %d", c.Code, c.Pixels, r.Dx()*r.Dy())
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestOffsets(t *testing.T) {
+	const width, height, col, row = 135, 240, 52, 40
+	for _, rotation := range rotations {
+		for _, software := range []bool{false, true} {
+			name := fmt.Sprintf("rotation%d/software=%v", rotation, software)
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+				opts := st7789.DefaultOpts
+				opts.Width, opts.Height = width, height
+				opts.ColumnOffset, opts.RowOffset = col, row
+				opts.Rotation = rotation
+				opts.SoftwareRotation = software
+				dev, p := newEmulatedWithOptions(t, opts)
+				img := pattern(dev.Bounds())
+				if err := dev.DrawRAW(img); err != nil {
+					t.Fatal(err)
+				}
+				shown, gram := p.Image(), p.GRAM()
+				b := dev.Bounds()
+				for y := b.Min.Y; y < b.Max.Y; y++ {
+					for x := b.Min.X; x < b.Max.X; x++ {
+						want := img.RGB565At(x, y)
+						px, py := panelPos(rotation, width, height, x, y)
+						if got := st7789.RGB565Model.Convert(shown.At(px, py)).(st7789.RGB565); got != want {
+							t.Fatalf("pixel %d,%d shown at %d,%d: got %#04x, want %#04x", x, y, px, py, got, want)
+						}
+						if got := st7789.RGB565Model.Convert(gram.At(px+col, py+row)).(st7789.RGB565); got != want {
+							t.Fatalf("pixel %d,%d in GRAM at %d,%d: got %#04x, want %#04x", x, y, px+col, py+row, got, want)
+						}
+					}
+				}
+				if rotation == st7789.ROTATION_90 || rotation == st7789.ROTATION_270 {
+					return
+				}
+
+				const top, bottom, line = 10, 20, 37
+				if err := dev.DefineScrollArea(top, height-top-bottom, bottom); err != nil {
+					t.Fatal(err)
+				}
+				if err := dev.ScrollTo(line); err != nil {
+					t.Fatal(err)
+				}
+				shown = p.Image()
+				for y := 0; y < height; y++ {
+					fbRow := dev.ScrolledRow(y)
+					for x := 0; x < width; x++ {
+						px, py := panelPos(rotation, width, height, x, y)
+						got := st7789.RGB565Model.Convert(shown.At(px, py)).(st7789.RGB565)
+						if want := img.RGB565At(x, fbRow); got != want {
+							t.Fatalf("scrolled pixel %d,%d got %#04x, want %#04x from row %d", x, y, got, want, fbRow)
+						}
+					}
+				}
+
+				if err := dev.ScrollTo(0); err != nil {
+					t.Fatal(err)
+				}
+				if err := dev.SetPartialArea(30, 99); err != nil {
+					t.Fatal(err)
+				}
+				if err := dev.SetPowerMode(st7789.POWER_PARTIAL); err != nil {
+					t.Fatal(err)
+				}
+				checkPartial(t, dev, p, rotation, img, 30, 99)
+			})
+		}
+	}
+}
+
+func TestOffsetsOutOfBounds(t *testing.T) {
+	for _, offset := range []image.Point{{106, 40}, {52, 81}, {-1, 0}, {0, -1}} {
+		opts := st7789.DefaultOpts
+		opts.Width, opts.Height = 135, 240
+		opts.ColumnOffset, opts.RowOffset = int16(offset.X), int16(offset.Y)
+		opts.Backlight = nil
+		if _, err := st7789.New(discard{}, &gpiotest.Pin{N: "DC"}, &opts); !errors.Is(err, st7789.ErrOutOfBounds) {
+			t.Errorf("offset %v: got %v, want ErrOutOfBounds", offset, err)
+		}
+	}
+}
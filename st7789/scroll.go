@@ -0,0 +1,113 @@
+package st7789
+
+import (
+	"fmt"
+	"image"
+)
+
+// gramRows is the number of rows in the controller memory, of which only
+// height rows from the row offset are visible.
+const gramRows = 320
+
+// DefineScrollArea splits the display rows in a fixed top band of top rows,
+// a scrolling area of scroll rows and a fixed bottom band of bottom rows.
+// They must add up to the display height. The controller scrolls rows of
+// the panel, so scrolling is only supported with ROTATION_NONE and
+// ROTATION_180; other rotations return an error.
+func (d *Device) DefineScrollArea(top, scroll, bottom int) error {
+	if err := d.checkScrollRotation(d.rotation); err != nil {
+		return err
+	}
+	if top < 0 || scroll <= 0 || bottom < 0 || top+scroll+bottom != int(d.height) {
+		return fmt.Errorf("%w: scroll area must cover the display height", ErrOutOfBounds)
+	}
+	old := [3]int{d.scrollTop, d.scrollHeight, d.scrollLine}
+	d.scrollTop = top
+	d.scrollHeight = scroll
+	d.scrollLine = 0
+	if err := d.sendScrollArea(); err != nil {
+		d.scrollTop, d.scrollHeight, d.scrollLine = old[0], old[1], old[2]
+		return err
+	}
+	return nil
+}
+
+// ScrollTo scrolls the area set with DefineScrollArea so that its first
+// visible row shows framebuffer row top+line (wrapping around the area).
+func (d *Device) ScrollTo(line int) error {
+	if d.scrollHeight == 0 {
+		return fmt.Errorf("%w: no scroll area defined", ErrOutOfBounds)
+	}
+	line %= d.scrollHeight
+	if line < 0 {
+		line += d.scrollHeight
+	}
+	d.scrollLine = line
+
+	return d.sendScrollStart()
+}
+
+// ScrolledRow returns the framebuffer row shown on display row row with the
+// current scroll position. After scrolling, drawing and flushing only the
+// rows returned for the newly exposed display rows updates the screen.
+func (d *Device) ScrolledRow(row int) int {
+	if d.scrollHeight == 0 || row < d.scrollTop || row >= d.scrollTop+d.scrollHeight {
+		return row
+	}
+	return d.scrollTop + (row-d.scrollTop+d.scrollLine)%d.scrollHeight
+}
+
+// ScrollArea returns the framebuffer area of the scrolling band, or an
+// empty rectangle if none is defined.
+func (d *Device) ScrollArea() image.Rectangle {
+	if d.scrollHeight == 0 {
+		return image.Rectangle{}
+	}
+	return image.Rect(d.rect.Min.X, d.scrollTop, d.rect.Max.X, d.scrollTop+d.scrollHeight)
+}
+
+// checkScrollRotation returns an error if a scroll area is, or is about to
+// be, defined with rotation, which turns panel rows into framebuffer
+// columns.
+func (d *Device) checkScrollRotation(rotation Rotation) error {
+	if rotation == ROTATION_90 || rotation == ROTATION_270 {
+		return fmt.Errorf("%w: scrolling with rotation %d", ErrUnsupported, rotation)
+	}
+	return nil
+}
+
+// sendScrollArea sends the scroll area and position. Panel rows run bottom
+// to top with ROTATION_180, so the fixed bands are swapped.
+func (d *Device) sendScrollArea() error {
+	tfa, bfa := d.scrollTop, int(d.height)-d.scrollTop-d.scrollHeight
+	if d.rotation == ROTATION_180 {
+		tfa, bfa = bfa, tfa
+	}
+	// The rows of memory that are never shown belong to the fixed bands
+	tfa += d.rowOffset
+	bfa += gramRows - int(d.height) - d.rowOffset
+
+	err := d.cmd(VSCRDEF,
+		byte(tfa>>8), byte(tfa&0xFF),
+		byte(d.scrollHeight>>8), byte(d.scrollHeight&0xFF),
+		byte(bfa>>8), byte(bfa&0xFF),
+	)
+	if err != nil {
+		return err
+	}
+	return d.sendScrollStart()
+}
+
+// sendScrollStart sends the memory row shown first in the scroll area.
+// With ROTATION_180 the area scrolls the other way on the panel.
+func (d *Device) sendScrollStart() error {
+	if d.scrollHeight == 0 {
+		return d.cmd(VSCSAD, verticalScrollOffset(0)...)
+	}
+	tfa, line := d.scrollTop, d.scrollLine
+	if d.rotation == ROTATION_180 {
+		tfa = int(d.height) - d.scrollTop - d.scrollHeight
+		line = (d.scrollHeight - line) % d.scrollHeight
+	}
+	return d.cmd(VSCSAD, verticalScrollOffset(d.rowOffset+tfa+line)...)
+}
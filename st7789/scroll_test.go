@@ -0,0 +1,80 @@
+package st7789_test
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"testing"
+
+	"github.com/rubiojr/go-pirateaudio/st7789"
+)
+
+func TestScroll(t *testing.T) {
+	const top, bottom = 10, 20
+	for _, size := range []image.Point{{240, 240}, {240, 320}} {
+		for _, rotation := range []st7789.Rotation{st7789.ROTATION_NONE, st7789.ROTATION_180} {
+			for _, software := range []bool{false, true} {
+				name := fmt.Sprintf("%dx%d/rotation%d/software=%v", size.X, size.Y, rotation, software)
+				t.Run(name, func(t *testing.T) {
+					t.Parallel()
+					dev, p := newEmulated(t, size.X, size.Y, rotation, software)
+					if err := dev.DefineScrollArea(top, size.Y-top-bottom, bottom); err != nil {
+						t.Fatal(err)
+					}
+					if want := image.Rect(0, top, size.X, size.Y-bottom); dev.ScrollArea() != want {
+						t.Errorf("scroll area %v, want %v", dev.ScrollArea(), want)
+					}
+					img := pattern(dev.Bounds())
+					if err := dev.DrawRAW(img); err != nil {
+						t.Fatal(err)
+					}
+
+					for _, line := range []int{0, 1, 37, -5} {
+						if err := dev.ScrollTo(line); err != nil {
+							t.Fatal(err)
+						}
+						shown := p.Image()
+						for row := 0; row < size.Y; row++ {
+							fbRow := dev.ScrolledRow(row)
+							if row >= top && row < size.Y-bottom {
+								want := top + ((row-top+line)%(size.Y-top-bottom)+size.Y-top-bottom)%(size.Y-top-bottom)
+								if fbRow != want {
+									t.Fatalf("line %d: row %d shows framebuffer row %d, want %d", line, row, fbRow, want)
+								}
+							} else if fbRow != row {
+								t.Fatalf("line %d: fixed row %d shows framebuffer row %d", line, row, fbRow)
+							}
+							for x := 0; x < size.X; x++ {
+								px, py := panelPos(rotation, size.X, size.Y, x, row)
+								got := st7789.RGB565Model.Convert(shown.At(px, py)).(st7789.RGB565)
+								if want := img.RGB565At(x, fbRow); got != want {
+									t.Fatalf("line %d: pixel %d,%d got %#04x, want %#04x from row %d", line, x, row, got, want, fbRow)
+								}
+							}
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestScrollRotated(t *testing.T) {
+	dev, _ := newEmulated(t, 240, 240, st7789.ROTATION_90, false)
+	if err := dev.DefineScrollArea(10, 200, 30); !errors.Is(err, st7789.ErrUnsupported) {
+		t.Errorf("DefineScrollArea with ROTATION_90: got %v, want ErrUnsupported", err)
+	}
+
+	if err := dev.SetRotation(st7789.ROTATION_NONE); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.DefineScrollArea(10, 200, 30); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.SetRotation(st7789.ROTATION_270); !errors.Is(err, st7789.ErrUnsupported) {
+		t.Errorf("SetRotation to ROTATION_270 with a scroll area: got %v, want ErrUnsupported", err)
+	}
+	if err := dev.SetRotation(st7789.ROTATION_180); err != nil {
+		t.Errorf("SetRotation to ROTATION_180 with a scroll area: %v", err)
+	}
+}
@@ -5,12 +5,10 @@ import (
 	"fmt"
 	"image"
 	"image/color"
-	"image/draw"
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
 	"io"
-	"log"
 	"time"
 
 	"periph.io/x/conn/v3"
@@ -20,11 +18,18 @@ import (
 	"periph.io/x/conn/v3/spi"
 )
 
-// DefaultOpts is the recommended default options.
+// maxTxSize is the largest transfer spidev accepts by default.
+const maxTxSize = 4096
+
+// DefaultOpts is the recommended default options, with the backlight on
+// GPIO13 as on the Pirate Audio boards.
 var DefaultOpts = Opts{
-	Width:    240,
-	Height:   240,
-	Rotation: ROTATION_NONE,
+	Width:     240,
+	Height:    240,
+	Rotation:  ROTATION_NONE,
+	Speed:     80 * physic.MegaHertz,
+	Mode:      spi.Mode0,
+	Backlight: namedPin("GPIO13"),
 }
 
 // Opts defines the options for the device.
@@ -32,31 +37,75 @@ type Opts struct {
 	Width    int16
 	Height   int16
 	Rotation Rotation
+	// SoftwareRotation rotates pixels in the driver instead of through the
+	// controller's memory access settings, for panels where those don't
+	// give the expected orientation. Flushes of rotated displays are a bit
+	// slower.
+	SoftwareRotation bool
+	// Dither is how images are reduced to 16-bit colors, see SetDither.
+	Dither Dither
+	// ColumnOffset and RowOffset are where the panel starts in the
+	// controller memory, in its native orientation, for panels smaller than
+	// the memory that don't start at its top left corner. 135x240 modules
+	// use 52 and 40.
+	ColumnOffset int16
+	RowOffset    int16
+	// Speed is the SPI clock, 80MHz if zero.
+	Speed physic.Frequency
+	Mode  spi.Mode
+	// Backlight is the pin driving the backlight, nil if not connected.
+	Backlight gpio.PinOut
+	// Reset is the pin connected to the controller's RESX, nil if not
+	// connected.
+	Reset gpio.PinOut
 }
 
+// NewSPI connects to port and initializes the display, using dataComm to
+// switch between commands and data.
 func NewSPI(port spi.Port, dataComm gpio.PinOut, opts *Opts) (*Device, error) {
 	if dataComm == gpio.INVALID {
-		return nil, errors.New("ssd1306: use nil for dc to use 3-wire mode, do not use gpio.INVALID")
+		return nil, errors.New("st7789: use nil for dc to use 3-wire mode, do not use gpio.INVALID")
 	}
 	bits := 8
 	if err := dataComm.Out(gpio.Low); err != nil {
 		return nil, err
 	}
-	conn, err := port.Connect(80*physic.MegaHertz, spi.Mode0, bits)
+	speed := opts.Speed
+	if speed == 0 {
+		speed = DefaultOpts.Speed
+	}
+	conn, err := port.Connect(speed, opts.Mode, bits)
 	if err != nil {
 		return nil, err
 	}
 
-	pin := gpioreg.ByName("GPIO13")
-	if err = pin.Out(gpio.Low); err != nil {
-		panic(err)
+	return New(conn, dataComm, opts)
+}
+
+// New initializes a display reachable through an already connected bus c,
+// using dataComm to switch between commands and data.
+func New(c conn.Conn, dataComm gpio.PinOut, opts *Opts) (*Device, error) {
+	if opts.Width <= 0 || opts.Height <= 0 || opts.ColumnOffset < 0 || opts.RowOffset < 0 ||
+		int(opts.ColumnOffset)+int(opts.Width) > gramCols || int(opts.RowOffset)+int(opts.Height) > gramRows {
+		return nil, fmt.Errorf("%w: %dx%d panel at %d,%d", ErrOutOfBounds, opts.Width, opts.Height, opts.ColumnOffset, opts.RowOffset)
 	}
-	time.Sleep(100 * time.Millisecond)
-	if err = pin.Out(gpio.High); err != nil {
-		panic(err)
+	if opts.Reset != nil {
+		if err := opts.Reset.Out(gpio.Low); err != nil {
+			return nil, err
+		}
+		time.Sleep(100 * time.Millisecond)
+		if err := opts.Reset.Out(gpio.High); err != nil {
+			return nil, err
+		}
+		time.Sleep(120 * time.Millisecond)
+	}
+	if opts.Backlight != nil {
+		if err := opts.Backlight.Out(gpio.High); err != nil {
+			return nil, err
+		}
 	}
 
-	return newST7789Device(conn, opts, dataComm)
+	return newST7789Device(c, opts, dataComm)
 }
 
 type Device struct {
@@ -64,14 +113,35 @@ type Device struct {
 	dataComm gpio.PinOut
 	rect     image.Rectangle
 
-	rotation                      Rotation
-	width                         int16
-	height                        int16
-	rowOffsetCfg, rowOffset       int16
-	columnOffset, columnOffsetCfg int16
-	isBGR                         bool
-	batchLength                   int32
-	backlight                     gpio.PinIO
+	rotation         Rotation
+	softwareRotation bool
+	dither           Dither
+	// width and height are the size of the panel, in its native orientation,
+	// and columnOffset and rowOffset where it starts in the memory
+	width        int16
+	height       int16
+	columnOffset int
+	rowOffset    int
+	isBGR        bool
+	batchLength  int32
+	backlight    gpio.PinOut
+
+	// vertical scrolling state, see DefineScrollArea
+	scrollTop, scrollHeight, scrollLine int
+
+	powerMode                PowerMode
+	partialStart, partialEnd int
+	sleepOut                 time.Time // last time SLPOUT was sent
+	sleepIn                  time.Time // last time SLPIN was sent
+
+	// fb is the shadow framebuffer, row-major in rotated coordinates, two
+	// bytes per pixel in the order they are sent to the display.
+	fb []byte
+	// Buffers reused between calls: a converted row, the data of a flush
+	// and images of types without a fast path.
+	row     []byte
+	tx      []byte
+	scratch *image.RGBA
 }
 
 func (d *Device) String() string {
@@ -85,121 +155,202 @@ func (d *Device) Bounds() image.Rectangle {
 
 // PowerOff the display
 func (d *Device) PowerOff() error {
-	return d.backlight.Out(gpio.Low)
+	return d.setBacklight(gpio.Low)
 }
 
 // PowerOn the display
 func (d *Device) PowerOn() error {
-	return d.backlight.Out(gpio.High)
+	return d.setBacklight(gpio.High)
 }
 
-// Invert the display (black on white vs white on black).
-func (d *Device) Invert(blackOnWhite bool) {
-	b := byte(0xA6)
-	if blackOnWhite {
-		b = 0xA7
+// setBacklight drives the backlight pin, if there is one.
+func (d *Device) setBacklight(l gpio.Level) error {
+	if d.backlight == nil {
+		return nil
 	}
-	d.Command(b)
+	return d.backlight.Out(l)
 }
 
-func newST7789Device(conn conn.Conn, opts *Opts, dataComm gpio.PinOut) (*Device, error) {
-	d := &Device{
-		conn:        conn,
-		dataComm:    dataComm,
-		rect:        image.Rect(0, 0, int(opts.Width), int(opts.Height)),
-		rotation:    opts.Rotation,
-		width:       opts.Width,
-		height:      opts.Height,
-		batchLength: int32(opts.Width),
-		backlight:   gpioreg.ByName("GPIO13"),
-	}
-	d.batchLength = d.batchLength & 1
-
-	d.Command(SWRESET)
-	time.Sleep(150 * time.Millisecond)
-
-	d.Command(MADCTL)
-	d.Data(MADCTL_MX_RL | MADCTL_MV_REV | MADCTL_ML_BT)
-
-	d.Command(PORCTRL)
-	d.SendData(defaultPorchControl())
-
-	d.Command(COLMOD)
-	d.Data(COLMOD_CTRL_65K)
-
-	d.Command(GCTRL)
-	d.Data(defaultGateControl())
+// namedPin is a pin looked up in periph's registry every time it is used,
+// as pins are only registered once the host drivers are initialized.
+type namedPin string
 
-	d.Command(VCOMS)
-	d.Data(defaulVCOMSOffsetSet())
+func (p namedPin) pin() (gpio.PinIO, error) {
+	if pin := gpioreg.ByName(string(p)); pin != nil {
+		return pin, nil
+	}
+	return nil, fmt.Errorf("st7789: unknown pin %q", string(p))
+}
 
-	d.Command(LCMCTRL)
-	d.Data(LCMCTRL_XBGR | LCMCTRL_XMH | LMCTRL_XMV)
+func (p namedPin) String() string { return string(p) }
+func (p namedPin) Name() string   { return string(p) }
 
-	d.Command(VDVVRHEN)
-	d.Data(VDVVRHEN_CMDEN_WRITE)
+func (p namedPin) Halt() error {
+	pin, err := p.pin()
+	if err != nil {
+		return err
+	}
+	return pin.Halt()
+}
 
-	d.Command(VRHS)
-	d.Data(defaultVRHSet())
+func (p namedPin) Number() int {
+	pin, err := p.pin()
+	if err != nil {
+		return -1
+	}
+	return pin.Number()
+}
 
-	d.Command(VDVS)
-	d.Data(defaultVDVSet())
+func (p namedPin) Function() string {
+	pin, err := p.pin()
+	if err != nil {
+		return ""
+	}
+	return pin.Function()
+}
 
-	d.Command(PWCTRL1)
-	d.SendData(defaultPowerCtrl())
+func (p namedPin) Out(l gpio.Level) error {
+	pin, err := p.pin()
+	if err != nil {
+		return err
+	}
+	return pin.Out(l)
+}
 
-	d.Command(FRCTRL2)
-	d.Data(FRAMERATE_60)
+func (p namedPin) PWM(duty gpio.Duty, f physic.Frequency) error {
+	pin, err := p.pin()
+	if err != nil {
+		return err
+	}
+	return pin.PWM(duty, f)
+}
 
-	d.Command(PVGAMCTRL)
-	d.SendData(defaultPositiveGammaCtrl())
+// Invert the display (black on white vs white on black).
+func (d *Device) Invert(blackOnWhite bool) error {
+	b := byte(0xA6)
+	if blackOnWhite {
+		b = 0xA7
+	}
+	return d.Command(b)
+}
 
-	d.Command(NVGAMCTRL)
-	d.SendData(defaultNegativeGammaCtrl())
+func newST7789Device(conn conn.Conn, opts *Opts, dataComm gpio.PinOut) (*Device, error) {
+	d := &Device{
+		conn:             conn,
+		dataComm:         dataComm,
+		rotation:         opts.Rotation % 4,
+		softwareRotation: opts.SoftwareRotation,
+		dither:           opts.Dither,
+		width:            opts.Width,
+		height:           opts.Height,
+		columnOffset:     int(opts.ColumnOffset),
+		rowOffset:        int(opts.RowOffset),
+		batchLength:      int32(opts.Width),
+		backlight:        opts.Backlight,
+		fb:               make([]byte, int(opts.Width)*int(opts.Height)*2),
+		partialEnd:       int(opts.Height) - 1,
+	}
+	d.rect = d.rotatedBounds(d.rotation)
+	d.batchLength = d.batchLength & 1
 
-	d.Command(INVON)
+	if err := d.Command(SWRESET); err != nil {
+		return nil, err
+	}
+	time.Sleep(150 * time.Millisecond)
 
-	d.Command(SLPOUT)
+	seq := []struct {
+		cmd  uint8
+		data []byte
+	}{
+		{MADCTL, []byte{d.madctl()}},
+		{PORCTRL, defaultPorchControl()},
+		{COLMOD, []byte{COLMOD_CTRL_65K}},
+		{GCTRL, []byte{defaultGateControl()}},
+		{VCOMS, []byte{defaulVCOMSOffsetSet()}},
+		{LCMCTRL, []byte{LCMCTRL_XBGR | LCMCTRL_XMH | LMCTRL_XMV}},
+		{VDVVRHEN, []byte{VDVVRHEN_CMDEN_WRITE}},
+		{VRHS, []byte{defaultVRHSet()}},
+		{VDVS, []byte{defaultVDVSet()}},
+		{PWCTRL1, defaultPowerCtrl()},
+		{FRCTRL2, []byte{FRAMERATE_60}},
+		{PVGAMCTRL, defaultPositiveGammaCtrl()},
+		{NVGAMCTRL, defaultNegativeGammaCtrl()},
+		{INVON, nil},
+		{SLPOUT, nil},
+	}
+	for _, c := range seq {
+		if err := d.cmd(c.cmd, c.data...); err != nil {
+			return nil, err
+		}
+	}
+	d.sleepOut = time.Now()
+	time.Sleep(sleepOutDelay)
 
-	d.Command(DISPON)
+	if err := d.Command(DISPON); err != nil {
+		return nil, err
+	}
 
 	return d, nil
 }
 
-func (d *Device) SetWindow() {
-	x1 := d.width - 1
-	y1 := d.height - 1
-	y0 := 0
-	x0 := 0
-
-	d.Command(CASET)
-	d.Data(byte(x0 >> 8))
-	d.Data(byte(x0 & 0xFF))
-	d.Data(byte(x1 >> 8))
-	d.Data(byte(x1 & 0xFF))
+// SetWindow sets the address window to the whole display and starts a
+// memory write.
+func (d *Device) SetWindow() error {
+	return d.setWindow(d.rect)
+}
 
-	d.Command(RASET)
-	d.Data(byte(y0 >> 8))
-	d.Data(byte(y0 & 0xFF))
-	d.Data(byte(y1 >> 8))
-	d.Data(byte(y1 & 0xFF))
+// setWindow programs the column/row address range covering r (in
+// framebuffer coordinates) and starts a memory write. With hardware
+// rotation the controller takes r row by row in framebuffer order, with
+// software rotation row by row in panel order.
+func (d *Device) setWindow(r image.Rectangle) error {
+	x0, y0 := d.addressOffset()
+	if d.softwareRotation {
+		r = d.panelRect(r)
+	}
+	x0, x1 := x0+r.Min.X, x0+r.Max.X-1
+	y0, y1 := y0+r.Min.Y, y0+r.Max.Y-1
 
-	d.Command(RAMWR)
-	d.Data(0x89)
+	if err := d.cmd(CASET, byte(x0>>8), byte(x0&0xFF), byte(x1>>8), byte(x1&0xFF)); err != nil {
+		return err
+	}
+	if err := d.cmd(RASET, byte(y0>>8), byte(y0&0xFF), byte(y1>>8), byte(y1&0xFF)); err != nil {
+		return err
+	}
+	return d.Command(RAMWR)
 }
 
+// SendData sends data bytes to the device. Errors wrap ErrBus.
 func (d *Device) SendData(c []byte) error {
 	if err := d.dataComm.Out(gpio.High); err != nil {
-		return err
+		return fmt.Errorf("%w: %w", ErrBus, err)
+	}
+	if err := d.conn.Tx(c, nil); err != nil {
+		return fmt.Errorf("%w: %w", ErrBus, err)
 	}
-	return d.conn.Tx(c, nil)
+	return nil
 }
 
+// SendCommand sends command bytes to the device. Errors wrap ErrBus.
 func (d *Device) SendCommand(c []byte) error {
 	if err := d.dataComm.Out(gpio.Low); err != nil {
+		return fmt.Errorf("%w: %w", ErrBus, err)
+	}
+	if err := d.conn.Tx(c, nil); err != nil {
+		return fmt.Errorf("%w: %w", ErrBus, err)
+	}
+	return nil
+}
+
+// cmd sends a command followed by its parameters, if any.
+func (d *Device) cmd(c uint8, data ...byte) error {
+	if err := d.Command(c); err != nil {
 		return err
 	}
-	return d.conn.Tx(c, nil)
+	if len(data) == 0 {
+		return nil
+	}
+	return d.SendData(data)
 }
 
 // FillRectangle fills a rectangle at a given coordinates with a color
@@ -207,36 +358,17 @@ func (d *Device) FillRectangle(x, y, width, height int16, c color.RGBA) error {
 	k, i := d.Size()
 	if x < 0 || y < 0 || width <= 0 || height <= 0 ||
 		x >= k || (x+width) > k || y >= i || (y+height) > i {
-		return errors.New("rectangle coordinates outside display area")
-	}
-	d.SetWindow()
-	c565 := RGBATo565(c)
-	c1 := uint8(c565)
-	c2 := uint8(c565 >> 8)
-
-	data := make([]uint8, d.PixelCount())
-	for i := int32(0); i < int32(d.width); i++ {
-		data[i*2] = c1
-		data[i*2+1] = c2
-	}
-	j := int32(width) * int32(height)
-	for j > 0 {
-		if j >= int32(d.height) {
-			d.SendData(data)
-		} else {
-			d.SendData(data[:j*2])
-		}
-		j -= int32(d.height)
+		return fmt.Errorf("%w: rectangle %d,%d %dx%d", ErrOutOfBounds, x, y, width, height)
 	}
-	return nil
+	r := image.Rect(int(x), int(y), int(x+width), int(y+height)).Intersect(d.rect)
+	d.fill(r, RGBATo565(c))
+	return d.FlushRegion(r)
 }
 
-// Size returns the current size of the display.
+// Size returns the current size of the display, which depends on the
+// rotation.
 func (d *Device) Size() (int16, int16) {
-	if d.rotation == ROTATION_NONE || d.rotation == ROTATION_180 {
-		return d.width, d.height
-	}
-	return d.height, d.width
+	return int16(d.rect.Dx()), int16(d.rect.Dy())
 }
 
 // PixelCount returns the number of pixels in the display
@@ -253,59 +385,14 @@ func RGBATo565(c color.RGBA) uint16 {
 }
 
 // SetPixel sets a pixel in the screen
-func (d *Device) SetPixel(x int16, y int16, c color.RGBA) {
-	if x < 0 || y < 0 ||
-		(((d.rotation == ROTATION_NONE || d.rotation == ROTATION_180) && (x >= d.width || y >= d.height)) ||
-			((d.rotation == ROTATION_90 || d.rotation == ROTATION_270) && (x >= d.height || y >= d.width))) {
-		return
-	}
-	d.FillRectangle(x, y, 1, 1, c)
+func (d *Device) SetPixel(x int16, y int16, c color.RGBA) error {
+	return d.FillRectangle(x, y, 1, 1, c)
 }
 
 // FillScreen fills the screen with a given color
-func (d *Device) FillScreen(c color.RGBA) {
-	if d.rotation == ROTATION_NONE || d.rotation == ROTATION_180 {
-		d.FillRectangle(0, 0, d.width, d.height, c)
-	} else {
-		d.FillRectangle(0, 0, d.height, d.width, c)
-	}
-}
-
-// SetRotation changes the rotation of the device (clock-wise)
-func (d *Device) SetRotation(rotation Rotation) {
-	madctl := uint8(0)
-	vscsad := verticalScrollOffset(0)
-	switch rotation % 4 {
-	case ROTATION_NONE:
-		madctl = MADCTL_MX_RL | MADCTL_MY_TB | MADCTL_MV_REV
-		d.rowOffset = d.rowOffsetCfg
-		d.columnOffset = d.columnOffsetCfg
-	case ROTATION_90:
-		madctl = MADCTL_MX_RL | MADCTL_MY_BT | MADCTL_MV_NORM
-		vscsad = verticalScrollOffset(320 - int(d.width))
-		d.rowOffset = d.columnOffsetCfg
-		d.columnOffset = d.rowOffsetCfg
-	case ROTATION_180:
-		madctl = MADCTL_MX_LR | MADCTL_MY_BT | MADCTL_MV_REV
-		vscsad = verticalScrollOffset(320 - int(d.width))
-		d.rowOffset = 0
-		d.columnOffset = 0
-	case ROTATION_270:
-		madctl = MADCTL_MX_LR | MADCTL_MY_TB | MADCTL_MV_NORM
-		d.rowOffset = 0
-		d.columnOffset = 0
-	}
-	if d.isBGR {
-		madctl |= MADCTL_BGR
-	}
-
-	// Set the display orientation
-	d.Command(MADCTL)
-	d.Data(madctl)
-
-	// Set vertical scroll offset so that images are located correctly on 240x240 displays
-	d.Command(VSCSAD)
-	d.SendData(vscsad)
+func (d *Device) FillScreen(c color.RGBA) error {
+	w, h := d.Size()
+	return d.FillRectangle(0, 0, w, h, c)
 }
 
 // IsBGR changes the color mode (RGB/BGR)
@@ -314,68 +401,74 @@ func (d *Device) IsBGR(bgr bool) {
 }
 
 // InverColors inverts the colors of the screen
-func (d *Device) InvertColors(invert bool) {
+func (d *Device) InvertColors(invert bool) error {
 	if invert {
-		d.Command(INVON)
-	} else {
-		d.Command(INVOFF)
+		return d.Command(INVON)
 	}
+	return d.Command(INVOFF)
 }
 
 // Command sends a command to the device
-func (d *Device) Command(cmd uint8) {
-	d.SendCommand([]byte{cmd})
+func (d *Device) Command(cmd uint8) error {
+	return d.SendCommand([]byte{cmd})
 }
 
 // Data sends data to the device
-func (d *Device) Data(data uint8) {
-	d.SendData([]byte{data})
+func (d *Device) Data(data uint8) error {
+	return d.SendData([]byte{data})
 }
 
 // DrawFastVLine draws a vertical line faster than using SetPixel
-func (d *Device) DrawFastVLine(x, y0, y1 int16, c color.RGBA) {
+func (d *Device) DrawFastVLine(x, y0, y1 int16, c color.RGBA) error {
 	if y0 > y1 {
 		y0, y1 = y1, y0
 	}
-	d.FillRectangle(x, y0, 1, y1-y0+1, c)
+	return d.FillRectangle(x, y0, 1, y1-y0+1, c)
 }
 
 // DrawFastHLine draws a horizontal line faster than using SetPixel
-func (d *Device) DrawFastHLine(x0, x1, y int16, c color.RGBA) {
+func (d *Device) DrawFastHLine(x0, x1, y int16, c color.RGBA) error {
 	if x0 > x1 {
 		x0, x1 = x1, x0
 	}
-	d.FillRectangle(x0, y, x1-x0+1, 1, c)
+	return d.FillRectangle(x0, y, x1-x0+1, 1, c)
 }
 
-func (d *Device) DrawImage(reader io.Reader) {
-	d.SetWindow()
+// DrawImage decodes an image and draws it covering the whole display.
+// Decoding errors wrap ErrDecode.
+func (d *Device) DrawImage(reader io.Reader) error {
 	img, _, err := image.Decode(reader)
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("%w: %w", ErrDecode, err)
 	}
 
-	d.DrawRAW(img)
+	return d.DrawRAW(img)
 }
 
-func (d *Device) DrawRAW(img image.Image) {
-	d.SetWindow()
-	rect := img.Bounds()
-	rgbaimg := image.NewRGBA(rect)
-	draw.Draw(rgbaimg, rect, img, rect.Min, draw.Src)
+// DrawRAW draws an image covering the whole display, in the current
+// rotation: the image is expected to be as large as Bounds.
+func (d *Device) DrawRAW(img image.Image) error {
+	return d.DrawRegion(d.Bounds(), img)
+}
 
-	np := []uint8{}
-	for i := 0; i < int(d.width); i++ {
-		for j := 0; j < int(d.height); j++ {
-			rgba := rgbaimg.At(rect.Min.X+int(d.width)-i, rect.Min.Y+j).(color.RGBA)
-			c565 := RGBATo565(rgba)
-			c1 := uint8(c565)
-			c2 := uint8(c565 >> 8)
-			np = append(np, c1, c2)
-		}
+// DrawRegion draws the part of img that falls within rect, leaving the rest
+// of the display untouched. Pixels are read from img at the same offset
+// from img.Bounds().Min as they have on the display.
+func (d *Device) DrawRegion(rect image.Rectangle, img image.Image) error {
+	rect = rect.Intersect(d.Bounds())
+	if rect.Empty() {
+		return fmt.Errorf("%w: region %v", ErrOutOfBounds, rect)
 	}
+	d.blit(rect, img, img.Bounds().Min.Add(rect.Min))
+	return d.FlushRegion(rect)
+}
 
-	for i := 0; i < len(np); i += 4096 {
-		d.SendData(np[i : i+4096])
+// sendChunked sends data in transfers no larger than maxTxSize.
+func (d *Device) sendChunked(data []byte) error {
+	for i := 0; i < len(data); i += maxTxSize {
+		if err := d.SendData(data[i:min(i+maxTxSize, len(data))]); err != nil {
+			return err
+		}
 	}
+	return nil
 }
@@ -0,0 +1,19 @@
+package st7789_test
+
+import (
+	"testing"
+
+	"github.com/rubiojr/go-pirateaudio/st7789"
+	"periph.io/x/conn/v3/gpio/gpiotest"
+)
+
+func TestDefaultBacklight(t *testing.T) {
+	if b := st7789.DefaultOpts.Backlight; b == nil || b.Name() != "GPIO13" {
+		t.Fatalf("default backlight is %v, want GPIO13", b)
+	}
+	// No host drivers are loaded, so the pin can't be found
+	opts := st7789.DefaultOpts
+	if _, err := st7789.New(discard{}, &gpiotest.Pin{N: "DC"}, &opts); err == nil {
+		t.Error("New succeeded without a GPIO13 pin, want an error")
+	}
+}
@@ -1,6 +1,8 @@
 package textview
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"strings"
 	"time"
@@ -28,6 +30,8 @@ type Options struct {
 	FontPath string
 	BGColor  Color
 	FGColor  Color
+	// Display to draw on, a new one with the default options if nil
+	Display *display.Display
 }
 
 var DefaultOpts = Options{
@@ -41,33 +45,56 @@ type Color [3]int
 var YELLOW = Color{255, 255, 0}
 var GREEN = Color{0, 255, 0}
 
+// ErrNoFont is returned when none of the fonts tried can be loaded.
+var ErrNoFont = errors.New("textview: could not load a valid TTF font")
+
+// New returns a TextView with the default options. It panics if the
+// display or the font cannot be set up, use Open to get an error instead.
 func New() *TextView {
 	return NewWithOptions(DefaultOpts)
 }
 
+// NewWithOptions returns a TextView with the given options. It panics if the
+// display or the font cannot be set up, use Open to get an error instead.
 func NewWithOptions(opts Options) *TextView {
+	tv, err := Open(opts)
+	if err != nil {
+		panic(err)
+	}
+	return tv
+}
+
+// Open returns a TextView with the given options. If opts.FontPath is empty
+// or cannot be read, a few common system fonts are tried, and ErrNoFont is
+// returned if none can be loaded.
+func Open(opts Options) (*TextView, error) {
 	tv := &TextView{FontSize: opts.FontSize}
 	tv.LineSep = 2
 	tv.vpos = uint8(tv.FontSize)
 	tv.margin = 2
 	tv.hpos = uint8(tv.margin)
 	tv.width = 240
-	tv.dc = gg.NewContext(tv.width, tv.width)
-	tv.loadFont(opts.FontPath)
-	tv.clearContext()
 	tv.bgColor = opts.BGColor
 	tv.fgColor = opts.FGColor
+	tv.dc = gg.NewContext(tv.width, tv.width)
+	if err := tv.loadFont(opts.FontPath); err != nil {
+		return nil, err
+	}
+	tv.clearContext()
 
-	var err error
-	tv.dsp, err = display.Init()
-	if err != nil {
-		panic(err)
+	tv.dsp = opts.Display
+	if tv.dsp == nil {
+		var err error
+		tv.dsp, err = display.Init()
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return tv
+	return tv, nil
 }
 
-func (t *TextView) loadFont(path string) {
+func (t *TextView) loadFont(path string) error {
 	fonts := []string{
 		path,
 		"/usr/share/fonts/truetype/roboto/unhinted/RobotoTTF/Roboto-Medium.ttf",
@@ -75,22 +102,18 @@ func (t *TextView) loadFont(path string) {
 		"/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf",
 	}
 
-	floaded := false
 	for _, f := range fonts {
 		if f == "" {
 			continue
 		}
 		if _, err := os.Stat(f); err == nil {
 			if err := t.dc.LoadFontFace(f, float64(t.FontSize)); err != nil {
-				panic(err)
+				return fmt.Errorf("%w: %s: %w", ErrNoFont, f, err)
 			}
-			floaded = true
-			break
+			return nil
 		}
 	}
-	if !floaded {
-		panic("could not load a valid TTF font")
-	}
+	return ErrNoFont
 }
 
 func (t *TextView) drawText(text string) {
@@ -112,14 +135,17 @@ func (t *TextView) drawText(text string) {
 	}
 }
 
-func (t *TextView) DrawChars(text string) {
+func (t *TextView) DrawChars(text string) error {
 	drawn := ""
 	for _, s := range text {
 		drawn += string(s)
-		t.Draw(drawn)
+		if err := t.Draw(drawn); err != nil {
+			return err
+		}
 		t.clearContext()
 		time.Sleep(50 * time.Millisecond)
 	}
+	return nil
 }
 
 func (t *TextView) clearContext() {
@@ -129,10 +155,10 @@ func (t *TextView) clearContext() {
 	t.dc.SetRGB255(t.fgColor[0], t.fgColor[1], t.fgColor[2])
 }
 
-func (t *TextView) Draw(text string) {
+func (t *TextView) Draw(text string) error {
 	t.drawText(text)
-	t.drawToDisplay(text)
-	t.resetPos()
+	defer t.resetPos()
+	return t.drawToDisplay(text)
 }
 
 func (t *TextView) resetPos() {
@@ -140,13 +166,16 @@ func (t *TextView) resetPos() {
 	t.hpos = uint8(t.margin)
 }
 
-func (t *TextView) drawToDisplay(text string) {
-	t.dsp.DrawRAW(t.dc.Image())
+func (t *TextView) drawToDisplay(text string) error {
+	return t.dsp.DrawRAW(t.dc.Image())
 }
 
-func (t *TextView) DrawFrames(textFrames []string) {
+func (t *TextView) DrawFrames(textFrames []string) error {
 	for _, f := range textFrames {
-		t.Draw(f)
+		if err := t.Draw(f); err != nil {
+			return err
+		}
 		time.Sleep(50 * time.Millisecond)
 	}
+	return nil
 }